@@ -7,11 +7,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	_ "testForWork/docs"
 	"testForWork/internal/api"
 	"testForWork/internal/config"
 	"testForWork/internal/database"
+	"testForWork/internal/repository"
+	"testForWork/internal/service"
 	"time"
 )
 
@@ -31,18 +34,47 @@ func main() {
 
 	cfg := config.LoadConfig()
 
-	log.Println("Loading database...")
-	db, err := database.ConnectAndSetup(cfg.DB)
+	var repositories repository.Repositories
+	switch cfg.Storage {
+	case config.StoragePostgres:
+		log.Println("Loading database...")
+		db, err := database.ConnectAndSetup(cfg.DB)
+		if err != nil {
+			log.Fatalf("Database connection failed: %v", err)
+		}
+		log.Println("Successfully connected to database")
+
+		defer db.Close()
+		defer log.Println("Database disconnected")
+
+		repositories = repository.NewPostgresRepositories(db)
+	case config.StorageMemory:
+		log.Println("Using in-memory storage, songs are lost on restart")
+		repositories = repository.NewMemoryRepositories()
+	default:
+		log.Fatalf("Unknown storage %q", cfg.Storage)
+	}
+
+	dates, err := service.NewDateParser(cfg.DateLayouts)
 	if err != nil {
-		log.Fatalf("Database connection failed: %v", err)
+		log.Fatalf("Release date layouts are invalid: %v", err)
 	}
-	log.Println("Successfully connected to database")
 
-	defer db.Close()
-	defer log.Println("Database disconnected")
+	details, err := service.NewDetailsProvider(cfg.Details, dates)
+	if err != nil {
+		log.Fatalf("Details provider setup failed: %v", err)
+	}
+	if names := service.DetailsProviderNames(details); len(names) == 1 {
+		log.Printf("Using %s details provider", names[0])
+	} else {
+		log.Printf("Using details provider chain %s", strings.Join(names, " -> "))
+	}
+
+	songService := service.NewService(repositories, details, dates, cfg.Timeouts)
+	songService.StartEnrichment(cfg.Enrichment)
+
+	handler := api.NewHandler(songService)
 
-	handler := api.NewHandler(db, cfg.MusicAPI)
-	
 	server := http.Server{
 		Addr:    ":" + cfg.Port,
 		Handler: handler.Routes(),
@@ -66,5 +98,8 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if err := songService.StopEnrichment(ctx); err != nil {
+		log.Printf("Enrichment shutdown: %v", err)
+	}
 	log.Println("Server stopped")
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testForWork/internal/models"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Fixtures is the content of the fixtures file.
+type Fixtures struct {
+	Songs []SongFixture `json:"songs" yaml:"songs"`
+	Rules []Rule        `json:"rules" yaml:"rules"`
+}
+
+type SongFixture struct {
+	Group       string `json:"group" yaml:"group"`
+	Song        string `json:"song" yaml:"song"`
+	ReleaseDate string `json:"release_date" yaml:"release_date"`
+	Text        string `json:"text" yaml:"text"`
+	Link        string `json:"link" yaml:"link"`
+}
+
+// Rule changes the answer for matching queries. Empty group or song match
+// any value; the first matching rule wins.
+type Rule struct {
+	Name        string  `json:"name" yaml:"name"`
+	Group       string  `json:"group" yaml:"group"`
+	Song        string  `json:"song" yaml:"song"`
+	Latency     string  `json:"latency" yaml:"latency"`
+	Status      int     `json:"status" yaml:"status"`
+	RetryAfter  int     `json:"retry_after" yaml:"retry_after"`
+	Probability float64 `json:"probability" yaml:"probability"`
+
+	latency time.Duration
+}
+
+func loadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures Fixtures
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &fixtures)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &fixtures)
+	default:
+		return nil, fmt.Errorf("unsupported fixtures format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for i := range fixtures.Rules {
+		rule := &fixtures.Rules[i]
+		if rule.Latency != "" {
+			if rule.latency, err = time.ParseDuration(rule.Latency); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid latency: %w", i, err)
+			}
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i+1)
+		}
+	}
+	return &fixtures, nil
+}
+
+func (fixtures *Fixtures) findSong(group, song string) (*models.SongDetail, bool) {
+	for _, fixture := range fixtures.Songs {
+		if strings.EqualFold(fixture.Group, group) && strings.EqualFold(fixture.Song, song) {
+			return &models.SongDetail{
+				ReleaseDate: fixture.ReleaseDate,
+				Text:        fixture.Text,
+				Link:        fixture.Link,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+func (fixtures *Fixtures) matchRule(group, song string) *Rule {
+	for i := range fixtures.Rules {
+		rule := &fixtures.Rules[i]
+		if rule.Group != "" && !strings.EqualFold(rule.Group, group) {
+			continue
+		}
+		if rule.Song != "" && !strings.EqualFold(rule.Song, song) {
+			continue
+		}
+		if applies(rule.Probability) {
+			return rule
+		}
+	}
+	return nil
+}
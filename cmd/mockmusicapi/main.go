@@ -0,0 +1,112 @@
+// Command mockmusicapi serves GET /info the way the music API spec describes,
+// answering from a fixtures file. Rules in the same file can slow down or
+// break responses to exercise retries and the circuit breaker locally.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", getEnv("MOCK_MUSIC_API_ADDR", ":8081"), "listen address")
+	fixturesPath := flag.String("fixtures", getEnv("MOCK_MUSIC_API_FIXTURES", "cmd/mockmusicapi/fixtures.yaml"), "fixtures file (.yaml, .yml or .json)")
+	flag.Parse()
+
+	fixtures, err := loadFixtures(*fixturesPath)
+	if err != nil {
+		log.Fatalf("Failed to load fixtures: %v", err)
+	}
+	log.Printf("Loaded %d songs and %d rules from %s", len(fixtures.Songs), len(fixtures.Rules), *fixturesPath)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /info", infoHandler(fixtures))
+
+	server := http.Server{
+		Addr:    *addr,
+		Handler: mux,
+	}
+
+	go func() {
+		log.Printf("Mock music API listening on %s", *addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down mock music API...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatalf("Mock music API forced to shutdown: %v", err)
+	}
+	log.Println("Mock music API stopped")
+}
+
+func infoHandler(fixtures *Fixtures) http.HandlerFunc {
+	return func(writer http.ResponseWriter, router *http.Request) {
+		group := router.URL.Query().Get("group")
+		song := router.URL.Query().Get("song")
+		if group == "" || song == "" {
+			log.Printf("GET /info group=%q song=%q -> 400", group, song)
+			http.Error(writer, "group and song are required", http.StatusBadRequest)
+			return
+		}
+
+		if rule := fixtures.matchRule(group, song); rule != nil {
+			if rule.latency > 0 {
+				select {
+				case <-time.After(rule.latency):
+				case <-router.Context().Done():
+					log.Printf("GET /info group=%q song=%q -> client gone", group, song)
+					return
+				}
+			}
+			if rule.Status != 0 && rule.Status != http.StatusOK {
+				if rule.RetryAfter > 0 {
+					writer.Header().Set("Retry-After", strconv.Itoa(rule.RetryAfter))
+				}
+				log.Printf("GET /info group=%q song=%q -> %d (rule %q)", group, song, rule.Status, rule.Name)
+				http.Error(writer, http.StatusText(rule.Status), rule.Status)
+				return
+			}
+		}
+
+		detail, ok := fixtures.findSong(group, song)
+		if !ok {
+			log.Printf("GET /info group=%q song=%q -> 404", group, song)
+			http.Error(writer, "Song not found", http.StatusNotFound)
+			return
+		}
+
+		log.Printf("GET /info group=%q song=%q -> 200", group, song)
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(detail)
+	}
+}
+
+// applies reports whether a rule with the given probability fires this time.
+func applies(probability float64) bool {
+	return probability <= 0 || probability >= 1 || rand.Float64() < probability
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
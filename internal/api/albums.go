@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"testForWork/internal/models"
+	"testForWork/internal/service"
+)
+
+// albumError answers the errors shared by the album endpoints.
+func albumError(writer http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrAlbumNotFound):
+		http.Error(writer, "Album not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrTrackGroup):
+		http.Error(writer, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		serverError(writer, action, err)
+	}
+}
+
+// @Summary Get albums
+// @Description Get albums with pagination
+// @Tags albums
+// @Produce json
+// @Param group_id query int false "Group filter"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {array} models.Album
+// @Router /albums [get]
+func (handler *Handler) getAlbums(writer http.ResponseWriter, router *http.Request) {
+	groupID, _ := strconv.Atoi(router.URL.Query().Get("group_id"))
+
+	page, _ := strconv.Atoi(router.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(router.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	albums, err := handler.service.GetAlbums(router.Context(), groupID, page, limit)
+	if err != nil {
+		albumError(writer, "getting albums", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(albums)
+}
+
+// @Summary Get album
+// @Tags albums
+// @Produce json
+// @Param id path int true "Album ID"
+// @Success 200 {object} models.Album
+// @Failure 404 {string} string "Album not found"
+// @Router /albums/{id} [get]
+func (handler *Handler) getAlbum(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	album, err := handler.service.GetAlbum(router.Context(), id)
+	if err != nil {
+		albumError(writer, "getting album", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(album)
+}
+
+// @Summary Add album
+// @Description Add an album. The group is found by name or created, type defaults to lp
+// @Tags albums
+// @Accept json
+// @Produce json
+// @Param album body models.AlbumRequest true "Album data, release_date accepts 2006, 2006-07, 2006-07-16 or 16.07.2006"
+// @Success 201 {object} models.Album
+// @Failure 400 {string} string "Bad Request"
+// @Router /albums [post]
+func (handler *Handler) addAlbum(writer http.ResponseWriter, router *http.Request) {
+	var request models.AlbumRequest
+	if err := json.NewDecoder(router.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request: %s\n", err)
+		http.Error(writer, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	album, err := handler.service.CreateAlbum(router.Context(), request)
+	if err != nil {
+		albumError(writer, "creating album", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	json.NewEncoder(writer).Encode(album)
+}
+
+// @Summary Update album
+// @Tags albums
+// @Accept json
+// @Produce json
+// @Param id path int true "Album ID"
+// @Param album body models.AlbumUpdateRequest true "Album data"
+// @Success 200 {object} models.Album
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Album not found"
+// @Failure 422 {string} string "The group changes while the album has tracks"
+// @Router /albums/{id} [put]
+func (handler *Handler) updateAlbum(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	var request models.AlbumUpdateRequest
+	if err := json.NewDecoder(router.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request: %s\n", err)
+		http.Error(writer, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	album, err := handler.service.UpdateAlbum(router.Context(), id, request)
+	if err != nil {
+		albumError(writer, "updating album", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(album)
+}
+
+// @Summary Delete album
+// @Description Delete an album, its songs are kept without an album
+// @Tags albums
+// @Param id path int true "Album ID"
+// @Success 204
+// @Failure 404 {string} string "Album not found"
+// @Router /albums/{id} [delete]
+func (handler *Handler) deleteAlbum(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	if err := handler.service.DeleteAlbum(router.Context(), id); err != nil {
+		albumError(writer, "deleting album", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Get album tracks
+// @Description Get songs of an album in tracklist order
+// @Tags albums
+// @Produce json
+// @Param id path int true "Album ID"
+// @Success 200 {array} models.Song
+// @Failure 404 {string} string "Album not found"
+// @Router /albums/{id}/tracks [get]
+func (handler *Handler) getAlbumTracks(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	songs, err := handler.service.GetAlbumTracks(router.Context(), id)
+	if err != nil {
+		albumError(writer, "getting album tracks", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(songs)
+}
+
+// @Summary Set album tracks
+// @Description Replace the tracklist, the first song becomes track 1. Songs left out lose the album, songs from other albums move here. All songs must belong to the group of the album
+// @Tags albums
+// @Accept json
+// @Produce json
+// @Param id path int true "Album ID"
+// @Param tracks body models.AlbumTracksRequest true "Song IDs in tracklist order"
+// @Success 200 {array} models.Song
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Album not found"
+// @Failure 422 {string} string "Song not found or of another group"
+// @Router /albums/{id}/tracks [put]
+func (handler *Handler) setAlbumTracks(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	var request models.AlbumTracksRequest
+	if err := json.NewDecoder(router.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request: %s\n", err)
+		http.Error(writer, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	songs, err := handler.service.SetAlbumTracks(router.Context(), id, request)
+	if err != nil {
+		albumError(writer, "setting album tracks", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(songs)
+}
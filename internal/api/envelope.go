@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"testForWork/internal/models"
+	"testForWork/internal/service"
+)
+
+// pageLink links to the current request with the query parameters in set
+// replaced and the ones in drop removed.
+func pageLink(router *http.Request, set map[string]string, drop ...string) string {
+	query := router.URL.Query()
+	for _, key := range drop {
+		query.Del(key)
+	}
+	for key, value := range set {
+		query.Set(key, value)
+	}
+	link := *router.URL
+	link.RawQuery = query.Encode()
+	return link.RequestURI()
+}
+
+// writeEnvelope writes the response leaving & in the links as it is.
+func writeEnvelope(writer http.ResponseWriter, envelope interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(envelope)
+}
+
+// pageInfo describes a page found by number.
+func pageInfo(router *http.Request, page, limit, total int) models.PageInfo {
+	info := models.PageInfo{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+		Links:      models.PageLinks{Self: router.URL.RequestURI()},
+	}
+	if page < info.TotalPages {
+		info.Links.Next = pageLink(router, map[string]string{"page": strconv.Itoa(page + 1)})
+	}
+	if page > 1 {
+		info.Links.Prev = pageLink(router, map[string]string{"page": strconv.Itoa(min(page-1, max(info.TotalPages, 1)))})
+	}
+	return info
+}
+
+// @Summary Get songs page
+// @Description Get songs with filtering and pagination in an envelope with the total and links to the neighbouring pages. Takes the same parameters as GET /songs, links follow cursors when the page was found by cursor
+// @Tags songs
+// @Produce json
+// @Param group query string false "Group filter"
+// @Param song_name query string false "Song filter"
+// @Param name_match query string false "How group and song are matched, ignoring case" Enums(exact, prefix, contains, fuzzy) default(exact)
+// @Param released query string false "Release date filter: 2006, 2006-07, 2006-07-16 or 16.07.2006"
+// @Param released_from query string false "Released on or after, in any release date layout"
+// @Param released_to query string false "Released on or before, 2000 takes in the whole year"
+// @Param created_from query string false "Added on or after: RFC 3339 time or a date"
+// @Param created_to query string false "Added before the end of: RFC 3339 time or a date"
+// @Param updated_since query string false "Updated on or after: RFC 3339 time or a date"
+// @Param has_link query bool false "Only songs with (true) or without (false) a link"
+// @Param has_text query bool false "Only songs with (true) or without (false) lyrics"
+// @Param album_id query int false "Album filter"
+// @Param sort query string false "Comma separated sort fields, - for descending: id, song, group, release_date, track_number, created_at, updated_at. Release dates sort by the end of their period, so 2006 follows 2006-03-05 and 2006-12 precedes 2006"
+// @Param genre query []string false "Genre filter, repeated or comma separated" collectionFormat(multi)
+// @Param tag query []string false "Tag filter, repeated or comma separated" collectionFormat(multi)
+// @Param match query string false "Whether songs need all or any of the genres and tags" Enums(all, any) default(all)
+// @Param after query string false "Cursor of the next page, replaces page"
+// @Param before query string false "Cursor of the previous page, replaces page"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} models.SongsEnvelope
+// @Failure 400 {string} string "Bad Request"
+// @Router /v2/songs [get]
+func (handler *Handler) getSongsPage(writer http.ResponseWriter, router *http.Request) {
+	filter := songFilter(router)
+
+	page, _ := strconv.Atoi(router.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(router.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	songs, err := handler.service.GetSongs(router.Context(), filter, page, limit)
+	if err != nil {
+		songListError(writer, err)
+		return
+	}
+	total, err := handler.service.CountSongs(router.Context(), filter)
+	if err != nil {
+		songListError(writer, err)
+		return
+	}
+
+	envelope := models.SongsEnvelope{Data: songs.Songs, PageInfo: pageInfo(router, page, limit, total)}
+	if envelope.Data == nil {
+		envelope.Data = []models.Song{}
+	}
+	if filter.After != "" || filter.Before != "" {
+		envelope.Page = 0
+		envelope.Links.Next, envelope.Links.Prev = "", ""
+		if songs.NextCursor != "" {
+			envelope.Links.Next = pageLink(router, map[string]string{"after": songs.NextCursor}, "before", "page")
+		}
+		if songs.PrevCursor != "" {
+			envelope.Links.Prev = pageLink(router, map[string]string{"before": songs.PrevCursor}, "after", "page")
+		}
+	}
+
+	writeEnvelope(writer, envelope)
+}
+
+// @Summary Get text page
+// @Description Get paginated song verses in an envelope with the number of verses and links to the neighbouring pages. A page past the last verse is 404, a song without text has empty pages
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} models.TextEnvelope
+// @Failure 404 {string} string "Song or page not found"
+// @Router /v2/songs/{id}/text [get]
+func (handler *Handler) getTextPage(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	page, _ := strconv.Atoi(router.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(router.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	verses, total, err := handler.service.GetText(router.Context(), id, page, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			http.Error(writer, "Song not found", http.StatusNotFound)
+			return
+		}
+
+		serverError(writer, "getting text", err)
+		return
+	}
+
+	info := pageInfo(router, page, limit, total)
+	if total > 0 && page > info.TotalPages {
+		http.Error(writer, "Page not found", http.StatusNotFound)
+		return
+	}
+
+	writeEnvelope(writer, models.TextEnvelope{Data: verses, PageInfo: info})
+}
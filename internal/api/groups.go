@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"testForWork/internal/models"
+	"testForWork/internal/service"
+)
+
+// groupError answers the errors shared by the group endpoints.
+func groupError(writer http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrGroupNotFound):
+		http.Error(writer, "Group not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrGroupExists), errors.Is(err, service.ErrGroupInUse):
+		http.Error(writer, err.Error(), http.StatusConflict)
+	default:
+		serverError(writer, action, err)
+	}
+}
+
+// @Summary Get groups
+// @Description Get groups with pagination
+// @Tags groups
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {array} models.Group
+// @Router /groups [get]
+func (handler *Handler) getGroups(writer http.ResponseWriter, router *http.Request) {
+	page, _ := strconv.Atoi(router.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(router.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	groups, err := handler.service.GetGroups(router.Context(), page, limit)
+	if err != nil {
+		groupError(writer, "getting groups", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(groups)
+}
+
+// @Summary Get group
+// @Tags groups
+// @Produce json
+// @Param id path int true "Group ID"
+// @Success 200 {object} models.Group
+// @Failure 404 {string} string "Group not found"
+// @Router /groups/{id} [get]
+func (handler *Handler) getGroup(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	group, err := handler.service.GetGroup(router.Context(), id)
+	if err != nil {
+		groupError(writer, "getting group", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(group)
+}
+
+// @Summary Add group
+// @Description Add a group. Names are unique ignoring case and surrounding spaces
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param group body models.GroupRequest true "Group data"
+// @Success 201 {object} models.Group
+// @Failure 400 {string} string "Bad Request"
+// @Failure 409 {string} string "Group already exists"
+// @Router /groups [post]
+func (handler *Handler) addGroup(writer http.ResponseWriter, router *http.Request) {
+	var request models.GroupRequest
+	if err := json.NewDecoder(router.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request: %s\n", err)
+		http.Error(writer, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	group, err := handler.service.CreateGroup(router.Context(), request)
+	if err != nil {
+		groupError(writer, "creating group", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusCreated)
+	json.NewEncoder(writer).Encode(group)
+}
+
+// @Summary Update group
+// @Description Update group details, renaming a group renames it for all its songs
+// @Tags groups
+// @Accept json
+// @Produce json
+// @Param id path int true "Group ID"
+// @Param group body models.GroupUpdateRequest true "Group data"
+// @Success 200 {object} models.Group
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Group not found"
+// @Failure 409 {string} string "Group already exists"
+// @Router /groups/{id} [put]
+func (handler *Handler) updateGroup(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	var request models.GroupUpdateRequest
+	if err := json.NewDecoder(router.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request: %s\n", err)
+		http.Error(writer, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	group, err := handler.service.UpdateGroup(router.Context(), id, request)
+	if err != nil {
+		groupError(writer, "updating group", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(group)
+}
+
+// @Summary Delete group
+// @Description Delete a group without songs and albums
+// @Tags groups
+// @Param id path int true "Group ID"
+// @Success 204
+// @Failure 404 {string} string "Group not found"
+// @Failure 409 {string} string "Group has songs or albums"
+// @Router /groups/{id} [delete]
+func (handler *Handler) deleteGroup(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	if err := handler.service.DeleteGroup(router.Context(), id); err != nil {
+		groupError(writer, "deleting group", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Get group songs
+// @Description Get songs of a group with pagination
+// @Tags groups
+// @Produce json
+// @Param id path int true "Group ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {array} models.Song
+// @Failure 404 {string} string "Group not found"
+// @Router /groups/{id}/songs [get]
+func (handler *Handler) getGroupSongs(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	page, _ := strconv.Atoi(router.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(router.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	songs, err := handler.service.GetGroupSongs(router.Context(), id, page, limit)
+	if err != nil {
+		groupError(writer, "getting group songs", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(songs)
+}
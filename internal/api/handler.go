@@ -1,30 +1,82 @@
 package api
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"html"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"testForWork/internal/models"
 	"testForWork/internal/service"
 )
 
 type Handler struct {
-	service  *service.Service
-	musicAPI string
+	service *service.Service
 }
 
-func NewHandler(db *sql.DB, musicAPI string) *Handler {
+func NewHandler(service *service.Service) *Handler {
 	return &Handler{
-		service:  service.NewService(db, musicAPI),
-		musicAPI: musicAPI,
+		service: service,
 	}
 }
 
+// serverError answers a failed operation. A request abandoned by the client
+// is only logged, an operation that ran out of time answers 504.
+func serverError(writer http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		log.Printf("Request cancelled by client while %s: %s\n", action, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("Timeout %s: %s\n", action, err)
+		http.Error(writer, "Gateway Timeout", http.StatusGatewayTimeout)
+	default:
+		log.Printf("Error %s: %s\n", action, err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// songListError answers the errors of both versions of the song list.
+func songListError(writer http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrInvalidInput) {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	serverError(writer, "getting songs", err)
+}
+
+// songFilter reads the song list filters shared by both versions of the list.
+func songFilter(router *http.Request) models.SongFilter {
+	filter := models.SongFilter{
+		Group:        router.URL.Query().Get("group"),
+		Song:         router.URL.Query().Get("song_name"),
+		Released:     router.URL.Query().Get("released"),
+		ReleasedFrom: router.URL.Query().Get("released_from"),
+		ReleasedTo:   router.URL.Query().Get("released_to"),
+		CreatedFrom:  router.URL.Query().Get("created_from"),
+		CreatedTo:    router.URL.Query().Get("created_to"),
+		UpdatedSince: router.URL.Query().Get("updated_since"),
+		HasLink:      router.URL.Query().Get("has_link"),
+		HasText:      router.URL.Query().Get("has_text"),
+		NameMatch:    router.URL.Query().Get("name_match"),
+		Sort:         router.URL.Query().Get("sort"),
+		After:        router.URL.Query().Get("after"),
+		Before:       router.URL.Query().Get("before"),
+		Genres:       queryList(router, "genre"),
+		Tags:         queryList(router, "tag"),
+		Match:        router.URL.Query().Get("match"),
+	}
+	filter.AlbumID, _ = strconv.Atoi(router.URL.Query().Get("album_id"))
+	return filter
+}
+
 // @Summary Get songs
 // @Description Get songs with filtering and pagination
 // @Tags songs
@@ -32,13 +84,31 @@ func NewHandler(db *sql.DB, musicAPI string) *Handler {
 // @Produce json
 // @Param group query string false "Group filter"
 // @Param song query string false "Song filter"
+// @Param name_match query string false "How group and song are matched, ignoring case. Fuzzy results are ordered by score" Enums(exact, prefix, contains, fuzzy) default(exact)
+// @Param released query string false "Release date filter: 2006, 2006-07, 2006-07-16 or 16.07.2006"
+// @Param released_from query string false "Released on or after, in any release date layout"
+// @Param released_to query string false "Released on or before, 2000 takes in the whole year"
+// @Param created_from query string false "Added on or after: RFC 3339 time or a date"
+// @Param created_to query string false "Added before the end of: RFC 3339 time or a date"
+// @Param updated_since query string false "Updated on or after: RFC 3339 time or a date"
+// @Param has_link query bool false "Only songs with (true) or without (false) a link"
+// @Param has_text query bool false "Only songs with (true) or without (false) lyrics"
+// @Param album_id query int false "Album filter"
+// @Param sort query string false "Comma separated sort fields, - for descending: id, song, group, release_date, track_number, created_at, updated_at. Release dates sort by the end of their period, so 2006 follows 2006-03-05 and 2006-12 precedes 2006"
+// @Param genre query []string false "Genre filter, repeated or comma separated" collectionFormat(multi)
+// @Param tag query []string false "Tag filter, repeated or comma separated" collectionFormat(multi)
+// @Param match query string false "Whether songs need all or any of the genres and tags" Enums(all, any) default(all)
+// @Param after query string false "Cursor from X-Next-Cursor, replaces page"
+// @Param before query string false "Cursor from X-Prev-Cursor, replaces page"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Success 200 {array} models.Song
+// @Header 200 {string} X-Next-Cursor "Cursor of the next page, absent on the last page"
+// @Header 200 {string} X-Prev-Cursor "Cursor of the previous page, absent on the first page"
+// @Failure 400 {string} string "Bad Request"
 // @Router /songs [get]
 func (handler *Handler) getSongs(writer http.ResponseWriter, router *http.Request) {
-	group := router.URL.Query().Get("group")
-	songName := router.URL.Query().Get("song_name")
+	filter := songFilter(router)
 
 	page, _ := strconv.Atoi(router.URL.Query().Get("page"))
 	if page < 1 {
@@ -50,25 +120,73 @@ func (handler *Handler) getSongs(writer http.ResponseWriter, router *http.Reques
 		limit = 10
 	}
 
-	songs, err := handler.service.GetSongs(group, songName, page, limit)
+	songs, err := handler.service.GetSongs(router.Context(), filter, page, limit)
 	if err != nil {
-		log.Printf("Error getting songs: %s\n", err)
-		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+		songListError(writer, err)
+		return
+	}
+	if songs.NextCursor != "" {
+		writer.Header().Set("X-Next-Cursor", songs.NextCursor)
+	}
+	if songs.PrevCursor != "" {
+		writer.Header().Set("X-Prev-Cursor", songs.PrevCursor)
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(songs.Songs)
+}
+
+// @Summary Search songs
+// @Description Full-text search over song names, group names and lyrics with english and russian stemming, best matches first. The snippet is the matching verse with matched words in <b></b>
+// @Tags songs
+// @Produce json
+// @Param q query string true "Search query: words, quoted phrases, or, -excluded words"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {array} models.SongSearchResult
+// @Failure 400 {string} string "Bad Request"
+// @Router /songs/search [get]
+func (handler *Handler) searchSongs(writer http.ResponseWriter, router *http.Request) {
+	page, _ := strconv.Atoi(router.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(router.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	results, err := handler.service.SearchSongs(router.Context(), router.URL.Query().Get("q"), page, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		serverError(writer, "searching songs", err)
 		return
 	}
 	writer.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(writer).Encode(songs)
+	json.NewEncoder(writer).Encode(results)
 }
 
 // @Summary Get text
-// @Description Get paginated song text
+// @Description Get paginated song text. By default the text is split into verses by blank lines, with format=sections the page holds typed sections (models.Section) instead. The verses are returned as the Accept header asks: a JSON array of strings (application/json, the default), raw text with blank lines between verses (text/plain), a paragraph per verse (text/html) or a JSON array of models.Verse with verse numbers (application/vnd.music-api.verses+json). Other types get 406
 // @Tags songs
 // @Accept json
 // @Produce json
+// @Produce plain
+// @Produce html
+// @Produce application/vnd.music-api.verses+json
 // @Param id path int true "Song ID"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
+// @Param format query string false "Response format" Enums(verses, sections) default(verses)
+// @Param type query string false "Section type filter, with format=sections" Enums(verse, pre-chorus, chorus, bridge, intro, outro, hook, other)
 // @Success 200 {array} string
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Song not found"
+// @Failure 406 {string} string "Not Acceptable"
 // @Router /songs/{id}/text [get]
 func (handler *Handler) getText(writer http.ResponseWriter, router *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
@@ -83,74 +201,234 @@ func (handler *Handler) getText(writer http.ResponseWriter, router *http.Request
 		limit = 10
 	}
 
-	text, err := handler.service.GetText(id, page, limit)
+	offers := []string{mediaJSON, mediaVerses, mediaPlain, mediaHTML}
+	format := router.URL.Query().Get("format")
+	switch format {
+	case "", "verses":
+	case "sections":
+		offers = []string{mediaJSON}
+	default:
+		http.Error(writer, "format must be verses or sections", http.StatusBadRequest)
+		return
+	}
+
+	writer.Header().Set("Vary", "Accept")
+	media, ok := negotiate(router.Header.Get("Accept"), offers)
+	if !ok {
+		http.Error(writer, "Not Acceptable, supported types: "+strings.Join(offers, ", "), http.StatusNotAcceptable)
+		return
+	}
+	if format == "sections" {
+		handler.getSections(writer, router, id, page, limit)
+		return
+	}
+
+	text, _, err := handler.service.GetText(router.Context(), id, page, limit)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, service.ErrNotFound) {
 			http.Error(writer, "Song not found", http.StatusNotFound)
 			return
 		}
 
-		log.Printf("Error getting text: %s\n", err)
-		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+		serverError(writer, "getting text", err)
+		return
+	}
+
+	switch media {
+	case mediaPlain:
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(writer, strings.Join(text, "\n\n"))
+	case mediaHTML:
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		for _, verse := range text {
+			lines := strings.Split(html.EscapeString(verse), "\n")
+			fmt.Fprintf(writer, "<p>%s</p>\n", strings.Join(lines, "<br>\n"))
+		}
+	case mediaVerses:
+		verses := make([]models.Verse, len(text))
+		for i, verse := range text {
+			verses[i] = models.Verse{Verse: (page-1)*limit + i, Text: verse}
+		}
+		writer.Header().Set("Content-Type", mediaVerses)
+		json.NewEncoder(writer).Encode(verses)
+	default:
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(text)
+	}
+}
+
+// @Summary Find in text
+// @Description Find every occurrence of a phrase in the song text with its verse and line, counted from 0, character offsets in the line and the page of GET /songs/{id}/text holding it for the given limit. The search ignores case by default
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param q query string true "Phrase to find"
+// @Param limit query int false "Verses per page" default(10)
+// @Param case_sensitive query bool false "Match case"
+// @Param whole_word query bool false "Match whole words only"
+// @Success 200 {array} models.TextMatch
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Song not found"
+// @Router /songs/{id}/text/find [get]
+func (handler *Handler) findInText(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	limit, _ := strconv.Atoi(router.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	matches, err := handler.service.FindInText(router.Context(), id, models.TextFind{
+		Query:         router.URL.Query().Get("q"),
+		CaseSensitive: router.URL.Query().Get("case_sensitive"),
+		WholeWord:     router.URL.Query().Get("whole_word"),
+	}, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrNotFound):
+			http.Error(writer, "Song not found", http.StatusNotFound)
+		default:
+			serverError(writer, "finding in text", err)
+		}
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(writer).Encode(text)
+	json.NewEncoder(writer).Encode(matches)
+}
+
+func (handler *Handler) getSections(writer http.ResponseWriter, router *http.Request, id, page, limit int) {
+	sections, _, err := handler.service.GetSections(router.Context(), id, router.URL.Query().Get("type"), page, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrNotFound):
+			http.Error(writer, "Song not found", http.StatusNotFound)
+		default:
+			serverError(writer, "getting sections", err)
+		}
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(sections)
 }
 
 // @Summary Add song
-// @Description Add new song
+// @Description Add new song. Release date, text and link are filled in asynchronously, see enrichment_status
 // @Tags songs
 // @Accept json
 // @Produce json
 // @Param song body models.SongRequest true "Song data"
-// @Success 201 {object} models.Song
+// @Param on_conflict query string false "When the group already has a song of that name: fail, return the existing song, or rename it to this spelling and enrich it again" Enums(error, return, update) default(error)
+// @Success 200 {object} models.Song "Existing song, on_conflict=return"
+// @Success 202 {object} models.Song
+// @Failure 400 {string} string "Bad Request"
+// @Failure 409 {string} string "Song already exists, Location points at it"
+// @Failure 413 {string} string "Song too large"
 // @Router /songs [post]
 func (handler *Handler) addSong(writer http.ResponseWriter, router *http.Request) {
 	var request models.SongRequest
-	if err := json.NewDecoder(router.Body).Decode(&request); err != nil {
-		log.Printf("Error decoding request: %s\n", err)
-		http.Error(writer, "Bad Request", http.StatusBadRequest)
+	if !decodeSong(writer, router, &request) {
 		return
 	}
 
-	newSong, err := handler.service.CreateSong(request.Group, request.Song)
-	log.Printf("successfully added song\n")
+	onConflict := router.URL.Query().Get("on_conflict")
+	newSong, created, err := handler.service.CreateSong(router.Context(), request.Group, request.Song, onConflict)
 	if err != nil {
-		log.Printf("Error creating song: %s\n", err)
-		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+		var conflict *service.SongConflictError
+		if errors.As(err, &conflict) {
+			writer.Header().Set("Location", fmt.Sprintf("/songs/%d", conflict.ID))
+			http.Error(writer, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidInput) {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		serverError(writer, "creating song", err)
 		return
 	}
 
+	status := http.StatusAccepted
+	if created {
+		log.Printf("successfully added song\n")
+	} else if strings.EqualFold(onConflict, models.OnConflictReturn) {
+		status = http.StatusOK
+	}
+
 	writer.Header().Set("Content-Type", "application/json")
-	writer.WriteHeader(http.StatusOK)
+	writer.WriteHeader(status)
 	json.NewEncoder(writer).Encode(newSong)
 }
 
+// maxSongSize bounds the song bodies; lyrics are diffed line by line, so
+// their size must stay reasonable.
+const maxSongSize = 1 << 20
+
+// decodeSong reads a song body of at most maxSongSize bytes, answering 413
+// or 400 and returning false when it cannot.
+func decodeSong(writer http.ResponseWriter, router *http.Request, request interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(writer, router.Body, maxSongSize)).Decode(request)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(writer, "Song too large", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	log.Printf("Error decoding request: %s\n", err)
+	http.Error(writer, "Bad Request", http.StatusBadRequest)
+	return false
+}
+
 // @Summary Update song
 // @Description Update song details
 // @Tags songs
 // @Accept json
 // @Produce json
 // @Param id path int true "Song ID"
-// @Param song body models.Song true "Song data"
+// @Param song body models.SongUpdateRequest true "Song data, release_date accepts 2006, 2006-07, 2006-07-16 or 16.07.2006"
 // @Success 200 {object} models.Song
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Song not found"
+// @Failure 409 {string} string "The group already has a song of that name"
+// @Failure 413 {string} string "Song too large"
+// @Failure 422 {string} string "The song is on an album of another group"
 // @Router /songs/{id} [put]
 func (handler *Handler) updateSong(writer http.ResponseWriter, router *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
 
 	var request models.SongUpdateRequest
-	if err := json.NewDecoder(router.Body).Decode(&request); err != nil {
-		log.Printf("Error decoding request: %s\n", err)
-		http.Error(writer, "Bad Request", http.StatusBadRequest)
+	if !decodeSong(writer, router, &request) {
 		return
 	}
 
-	updatedSong, err := handler.service.UpdateSong(id, request)
+	updatedSong, err := handler.service.UpdateSong(router.Context(), id, request)
 	if err != nil {
-		log.Printf("Error updating song: %s\n", err)
-		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+		if errors.Is(err, service.ErrInvalidInput) {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, service.ErrNotFound) {
+			http.Error(writer, "Song not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrSongExists) {
+			http.Error(writer, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, service.ErrTrackGroup) {
+			http.Error(writer, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		serverError(writer, "updating song", err)
 		return
 	}
 
@@ -165,17 +443,160 @@ func (handler *Handler) updateSong(writer http.ResponseWriter, router *http.Requ
 // @Produce json
 // @Param id path int true "Song ID"
 // @Success 204
+// @Failure 404 {string} string "Song not found"
 // @Router /songs/{id} [delete]
 func (handler *Handler) deleteSong(writer http.ResponseWriter, router *http.Request) {
 	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
 
-	if err := handler.service.DeleteSong(id); nil != err {
-		log.Printf("Error deleting song: %s\n", err)
-		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+	if err := handler.service.DeleteSong(router.Context(), id); nil != err {
+		if errors.Is(err, service.ErrNotFound) {
+			http.Error(writer, "Song not found", http.StatusNotFound)
+			return
+		}
+		serverError(writer, "deleting song", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+}
+
+// @Summary Re-enrich song
+// @Description Fetch fresh details from the details provider and update the song
+// @Tags songs
+// @Produce json
+// @Param id path int true "Song ID"
+// @Success 200 {object} models.EnrichmentResult
+// @Failure 404 {string} string "Song not found"
+// @Failure 502 {string} string "Details provider failed"
+// @Router /songs/{id}/enrich [post]
+func (handler *Handler) enrichSong(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	result, err := handler.service.EnrichSong(router.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			http.Error(writer, "Song not found", http.StatusNotFound)
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			serverError(writer, "enriching song", err)
+		case errors.Is(err, service.ErrDetailsFetch):
+			log.Printf("Error enriching song: %s\n", err)
+			http.Error(writer, "Details provider failed", http.StatusBadGateway)
+		default:
+			serverError(writer, "enriching song", err)
+		}
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(result)
+}
+
+// @Summary Re-enrich songs
+// @Description Re-enrich every song matching the filters, at least one filter is required
+// @Tags songs
+// @Produce json
+// @Param group query string false "Group filter"
+// @Param song_name query string false "Song filter"
+// @Success 200 {array} models.EnrichmentResult
+// @Failure 400 {string} string "Bad Request"
+// @Router /songs/enrich [post]
+func (handler *Handler) enrichSongs(writer http.ResponseWriter, router *http.Request) {
+	group := router.URL.Query().Get("group")
+	songName := router.URL.Query().Get("song_name")
+
+	results, err := handler.service.EnrichSongs(router.Context(), group, songName)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		serverError(writer, "enriching songs", err)
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(results)
+}
+
+// @Summary Details provider status
+// @Description Get circuit breaker state of every remote details provider
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.CircuitBreakerStatus
+// @Router /admin/details/status [get]
+func (handler *Handler) getDetailsStatus(writer http.ResponseWriter, router *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(handler.service.DetailsStatus())
+}
+
+// @Summary Details cache
+// @Description Get details cache statistics and entries
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.DetailsCache
+// @Failure 404 {string} string "Details cache is disabled"
+// @Router /admin/cache [get]
+func (handler *Handler) getDetailsCache(writer http.ResponseWriter, router *http.Request) {
+	cache, ok := handler.service.DetailsCache()
+	if !ok {
+		http.Error(writer, "Details cache is disabled", http.StatusNotFound)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(models.DetailsCache{
+		Stats:   cache.Stats(),
+		Entries: cache.Entries(),
+	})
+}
+
+// @Summary Purge details cache
+// @Description Purge the whole cache, all songs of a group or a single song
+// @Tags admin
+// @Produce json
+// @Param group query string false "Group to purge"
+// @Param song query string false "Song to purge, requires group"
+// @Success 200 {object} models.CachePurgeResult
+// @Failure 404 {string} string "Details cache is disabled"
+// @Router /admin/cache [delete]
+func (handler *Handler) purgeDetailsCache(writer http.ResponseWriter, router *http.Request) {
+	cache, ok := handler.service.DetailsCache()
+	if !ok {
+		http.Error(writer, "Details cache is disabled", http.StatusNotFound)
+		return
+	}
+
+	group := router.URL.Query().Get("group")
+	song := router.URL.Query().Get("song")
+	if group == "" && song != "" {
+		http.Error(writer, "song filter requires group", http.StatusBadRequest)
+		return
+	}
+
+	purged := cache.Purge(group, song)
+	log.Printf("Purged %d details cache entries\n", purged)
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(models.CachePurgeResult{Purged: purged})
+}
+
+// @Summary Import lyrics sections
+// @Description Parse the typed sections of every song stored with text but without sections
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.SectionImportResult
+// @Router /admin/sections/import [post]
+func (handler *Handler) importSections(writer http.ResponseWriter, router *http.Request) {
+	imported, err := handler.service.ImportSections(router.Context())
+	if err != nil {
+		serverError(writer, "importing sections", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(models.SectionImportResult{Imported: imported})
 }
 
 func (handler *Handler) Routes() chi.Router {
@@ -190,12 +611,64 @@ func (handler *Handler) Routes() chi.Router {
 	router.Route("/songs", func(r chi.Router) {
 		r.Get("/", handler.getSongs)
 		r.Post("/", handler.addSong)
+		r.Get("/search", handler.searchSongs)
+		r.Post("/enrich", handler.enrichSongs)
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/text", handler.getText)
+			r.Get("/text/find", handler.findInText)
+			r.Get("/text/revisions", handler.getRevisions)
+			r.Get("/text/revisions/diff", handler.diffRevisions)
+			r.Get("/text/revisions/{rev}", handler.getRevision)
+			r.Post("/text/revisions/{rev}/restore", handler.restoreRevision)
+			r.Get("/lyrics/synced", handler.getSyncedLyrics)
+			r.Put("/lyrics/synced", handler.setSyncedLyrics)
+			r.Post("/enrich", handler.enrichSong)
 			r.Put("/", handler.updateSong)
 			r.Delete("/", handler.deleteSong)
+			r.Post("/genres", handler.addSongGenres)
+			r.Delete("/genres/{name}", handler.deleteSongGenre)
+			r.Post("/tags", handler.addSongTags)
+			r.Delete("/tags/{name}", handler.deleteSongTag)
+		})
+	})
+
+	router.Route("/groups", func(r chi.Router) {
+		r.Get("/", handler.getGroups)
+		r.Post("/", handler.addGroup)
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", handler.getGroup)
+			r.Put("/", handler.updateGroup)
+			r.Delete("/", handler.deleteGroup)
+			r.Get("/songs", handler.getGroupSongs)
+		})
+	})
+
+	router.Route("/albums", func(r chi.Router) {
+		r.Get("/", handler.getAlbums)
+		r.Post("/", handler.addAlbum)
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", handler.getAlbum)
+			r.Put("/", handler.updateAlbum)
+			r.Delete("/", handler.deleteAlbum)
+			r.Get("/tracks", handler.getAlbumTracks)
+			r.Put("/tracks", handler.setAlbumTracks)
 		})
 	})
 
+	router.Get("/genres", handler.getGenres)
+	router.Get("/tags", handler.getTags)
+
+	router.Route("/v2", func(r chi.Router) {
+		r.Get("/songs", handler.getSongsPage)
+		r.Get("/songs/{id}/text", handler.getTextPage)
+	})
+
+	router.Route("/admin", func(r chi.Router) {
+		r.Get("/details/status", handler.getDetailsStatus)
+		r.Get("/cache", handler.getDetailsCache)
+		r.Delete("/cache", handler.purgeDetailsCache)
+		r.Post("/sections/import", handler.importSections)
+	})
+
 	return router
 }
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testForWork/internal/config"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+	"testForWork/internal/service"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	middleware.DefaultLogger = middleware.RequestLogger(&middleware.DefaultLogFormatter{Logger: log.New(io.Discard, "", 0)})
+	os.Exit(m.Run())
+}
+
+// testSong holds the fields of a song answer the tests look at.
+type testSong struct {
+	ID      int    `json:"id"`
+	GroupID int    `json:"group_id"`
+	Group   string `json:"group"`
+	Song    string `json:"song"`
+	AlbumID *int   `json:"album_id"`
+	Text    string `json:"text"`
+}
+
+// newTestRouter serves the API over in-memory repositories.
+func newTestRouter(t *testing.T) http.Handler {
+	t.Helper()
+	dates, err := service.NewDateParser(nil)
+	if err != nil {
+		t.Fatalf("NewDateParser: %v", err)
+	}
+	songService := service.NewService(repository.NewMemoryRepositories(), &service.NoneDetailsProvider{}, dates, config.TimeoutsConfig{})
+	return NewHandler(songService).Routes()
+}
+
+// serve sends the request with a JSON body unless body is empty.
+func serve(router http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range header {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, value interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(recorder.Body).Decode(value); err != nil {
+		t.Fatalf("decoding %q: %v", recorder.Body.String(), err)
+	}
+}
+
+// addSong creates the song and sets its text.
+func addSong(t *testing.T, router http.Handler, group, song, text string) testSong {
+	t.Helper()
+	recorder := serve(router, http.MethodPost, "/songs", fmt.Sprintf(`{"group":%q,"song":%q}`, group, song), nil)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("POST /songs = %d %s", recorder.Code, recorder.Body)
+	}
+	var created testSong
+	decode(t, recorder, &created)
+
+	if text != "" {
+		recorder = serve(router, http.MethodPut, fmt.Sprintf("/songs/%d", created.ID), fmt.Sprintf(`{"text":%q}`, text), nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("PUT /songs/%d = %d %s", created.ID, recorder.Code, recorder.Body)
+		}
+		decode(t, recorder, &created)
+	}
+	return created
+}
+
+func TestAddSong(t *testing.T) {
+	router := newTestRouter(t)
+	song := addSong(t, router, "Muse", "Uprising", "")
+	if song.ID == 0 || song.Group != "Muse" || song.Song != "Uprising" {
+		t.Fatalf("created song = %+v", song)
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		status   int
+		location string
+	}{
+		{name: "conflict", target: "/songs", body: `{"group":"muse","song":"uprising"}`, status: http.StatusConflict, location: fmt.Sprintf("/songs/%d", song.ID)},
+		{name: "return existing", target: "/songs?on_conflict=return", body: `{"group":"Muse","song":"Uprising"}`, status: http.StatusOK},
+		{name: "missing song", target: "/songs", body: `{"group":"Muse"}`, status: http.StatusBadRequest},
+		{name: "malformed", target: "/songs", body: `{"group":`, status: http.StatusBadRequest},
+		{name: "too large", target: "/songs", body: `{"group":"Muse","song":"` + strings.Repeat("a", 2<<20) + `"}`, status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(router, http.MethodPost, test.target, test.body, nil)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d %s, want %d", recorder.Code, recorder.Body, test.status)
+			}
+			if location := recorder.Header().Get("Location"); location != test.location {
+				t.Errorf("Location = %q, want %q", location, test.location)
+			}
+		})
+	}
+}
+
+func TestGetText(t *testing.T) {
+	router := newTestRouter(t)
+	song := addSong(t, router, "Muse", "Uprising", "One\nTwo\n\nThree <b>\n\nFour")
+	target := fmt.Sprintf("/songs/%d/text", song.ID)
+
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{name: "json", target: target + "?limit=2", status: http.StatusOK, contentType: "application/json", body: `["One\nTwo","Three \u003cb\u003e"]` + "\n"},
+		{name: "second page", target: target + "?page=2&limit=2", status: http.StatusOK, contentType: "application/json", body: `["Four"]` + "\n"},
+		{name: "verses", target: target + "?page=2&limit=2", accept: mediaVerses, status: http.StatusOK, contentType: mediaVerses, body: `[{"verse":2,"text":"Four"}]` + "\n"},
+		{name: "plain", target: target + "?limit=2", accept: "text/plain", status: http.StatusOK, contentType: "text/plain; charset=utf-8", body: "One\nTwo\n\nThree <b>"},
+		{name: "html", target: target + "?limit=2", accept: "text/html", status: http.StatusOK, contentType: "text/html; charset=utf-8", body: "<p>One<br>\nTwo</p>\n<p>Three &lt;b&gt;</p>\n"},
+		{name: "not acceptable", target: target, accept: "image/png", status: http.StatusNotAcceptable},
+		{name: "sections only as json", target: target + "?format=sections", accept: "text/plain", status: http.StatusNotAcceptable},
+		{name: "unknown format", target: target + "?format=lines", status: http.StatusBadRequest},
+		{name: "unknown song", target: "/songs/999/text", status: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(router, http.MethodGet, test.target, "", map[string]string{"Accept": test.accept})
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d %s, want %d", recorder.Code, recorder.Body, test.status)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != test.contentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, test.contentType)
+			}
+			if body := recorder.Body.String(); body != test.body {
+				t.Errorf("body = %q, want %q", body, test.body)
+			}
+		})
+	}
+}
+
+func TestGetTextPage(t *testing.T) {
+	router := newTestRouter(t)
+	song := addSong(t, router, "Muse", "Uprising", "One\n\nTwo\n\nThree")
+	empty := addSong(t, router, "Muse", "Exogenesis", "")
+
+	tests := []struct {
+		name       string
+		target     string
+		status     int
+		data       []string
+		totalPages int
+	}{
+		{name: "first page", target: fmt.Sprintf("/v2/songs/%d/text?limit=2", song.ID), status: http.StatusOK, data: []string{"One", "Two"}, totalPages: 2},
+		{name: "last page", target: fmt.Sprintf("/v2/songs/%d/text?page=2&limit=2", song.ID), status: http.StatusOK, data: []string{"Three"}, totalPages: 2},
+		{name: "past the last verse", target: fmt.Sprintf("/v2/songs/%d/text?page=3&limit=2", song.ID), status: http.StatusNotFound},
+		{name: "song without text", target: fmt.Sprintf("/v2/songs/%d/text?page=2", empty.ID), status: http.StatusOK, data: []string{}},
+		{name: "unknown song", target: "/v2/songs/999/text", status: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(router, http.MethodGet, test.target, "", nil)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d %s, want %d", recorder.Code, recorder.Body, test.status)
+			}
+			if test.status != http.StatusOK {
+				return
+			}
+			var envelope models.TextEnvelope
+			decode(t, recorder, &envelope)
+			if strings.Join(envelope.Data, "|") != strings.Join(test.data, "|") || envelope.TotalPages != test.totalPages {
+				t.Errorf("envelope = %+v, want data %q and %d pages", envelope, test.data, test.totalPages)
+			}
+		})
+	}
+}
+
+func TestSongSections(t *testing.T) {
+	router := newTestRouter(t)
+	song := addSong(t, router, "Muse", "Uprising", "[Verse]\nOne\n\n[Chorus]\nLa\n\n[Chorus]")
+
+	recorder := serve(router, http.MethodGet, fmt.Sprintf("/songs/%d/text?format=sections&type=chorus", song.ID), "", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", recorder.Code, recorder.Body)
+	}
+	var sections []models.Section
+	decode(t, recorder, &sections)
+	if len(sections) != 2 || sections[0].Position != 2 || sections[1].Position != 3 {
+		t.Fatalf("sections = %+v", sections)
+	}
+	if strings.Join(sections[1].Lines, "|") != "La" {
+		t.Errorf("repeated chorus lines = %q, want the first chorus", sections[1].Lines)
+	}
+}
+
+func TestRevisionDiff(t *testing.T) {
+	router := newTestRouter(t)
+	song := addSong(t, router, "Muse", "Uprising", "One\nTwo\nThree")
+	recorder := serve(router, http.MethodPut, fmt.Sprintf("/songs/%d", song.ID), `{"text":"One\nTwo and a half\nThree"}`, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("PUT = %d %s", recorder.Code, recorder.Body)
+	}
+
+	recorder = serve(router, http.MethodGet, fmt.Sprintf("/songs/%d/text/revisions/diff", song.ID), "", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("diff = %d %s", recorder.Code, recorder.Body)
+	}
+	var diff models.TextDiff
+	decode(t, recorder, &diff)
+	if diff.From != 1 || diff.To != 2 || diff.Added != 1 || diff.Removed != 1 || len(diff.Lines) != 4 {
+		t.Errorf("diff = %+v", diff)
+	}
+
+	recorder = serve(router, http.MethodGet, fmt.Sprintf("/songs/%d/text/revisions/diff?from=5", song.ID), "", nil)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("diff from a missing revision = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestAlbumTrackGroups(t *testing.T) {
+	router := newTestRouter(t)
+	track := addSong(t, router, "Muse", "Uprising", "")
+	loose := addSong(t, router, "Placebo", "Meds", "")
+
+	recorder := serve(router, http.MethodPost, "/albums", `{"group":"Muse","title":"The Resistance","release_date":"2009"}`, nil)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("POST /albums = %d %s", recorder.Code, recorder.Body)
+	}
+	var album struct {
+		ID int `json:"id"`
+	}
+	decode(t, recorder, &album)
+	albumPath := fmt.Sprintf("/albums/%d", album.ID)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{name: "track of another group", method: http.MethodPut, target: albumPath + "/tracks", body: fmt.Sprintf(`{"song_ids":[%d]}`, loose.ID), status: http.StatusUnprocessableEntity},
+		{name: "track of the album group", method: http.MethodPut, target: albumPath + "/tracks", body: fmt.Sprintf(`{"song_ids":[%d]}`, track.ID), status: http.StatusOK},
+		{name: "track moved to another group", method: http.MethodPut, target: fmt.Sprintf("/songs/%d", track.ID), body: `{"group":"Placebo"}`, status: http.StatusUnprocessableEntity},
+		{name: "album with tracks moved to another group", method: http.MethodPut, target: albumPath, body: `{"group":"Placebo"}`, status: http.StatusUnprocessableEntity},
+		{name: "track renamed", method: http.MethodPut, target: fmt.Sprintf("/songs/%d", track.ID), body: `{"song":"Uprising (Live)"}`, status: http.StatusOK},
+		{name: "tracks cleared", method: http.MethodPut, target: albumPath + "/tracks", body: `{"song_ids":[]}`, status: http.StatusOK},
+		{name: "empty album moved to another group", method: http.MethodPut, target: albumPath, body: `{"group":"Placebo"}`, status: http.StatusOK},
+		{name: "unknown album", method: http.MethodPut, target: "/albums/999/tracks", body: `{"song_ids":[]}`, status: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serve(router, test.method, test.target, test.body, nil)
+			if recorder.Code != test.status {
+				t.Errorf("status = %d %s, want %d", recorder.Code, recorder.Body, test.status)
+			}
+		})
+	}
+}
+
+func TestGetSongsPage(t *testing.T) {
+	router := newTestRouter(t)
+	for _, date := range []string{"2006", "2006-12", "2005-06-01", "2006-12-31", "2006-03-05"} {
+		song := addSong(t, router, "Muse", "Song "+date, "")
+		recorder := serve(router, http.MethodPut, fmt.Sprintf("/songs/%d", song.ID), fmt.Sprintf(`{"release_date":%q}`, date), nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("PUT = %d %s", recorder.Code, recorder.Body)
+		}
+	}
+	ascending := []string{"Song 2005-06-01", "Song 2006-03-05", "Song 2006-12-31", "Song 2006-12", "Song 2006"}
+
+	// the first page comes from /songs, the cursor in its header leads
+	// through the /v2 pages
+	recorder := serve(router, http.MethodGet, "/songs?sort=release_date&limit=2", "", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /songs = %d %s", recorder.Code, recorder.Body)
+	}
+	var first []testSong
+	decode(t, recorder, &first)
+	var names []string
+	for _, song := range first {
+		names = append(names, song.Song)
+	}
+
+	target := "/v2/songs?sort=release_date&limit=2&after=" + recorder.Header().Get("X-Next-Cursor")
+	for target != "" {
+		recorder := serve(router, http.MethodGet, target, "", nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", target, recorder.Code, recorder.Body)
+		}
+		var envelope struct {
+			Data  []testSong       `json:"data"`
+			Total int              `json:"total"`
+			Links models.PageLinks `json:"links"`
+		}
+		decode(t, recorder, &envelope)
+		if envelope.Total != len(ascending) {
+			t.Fatalf("total = %d, want %d", envelope.Total, len(ascending))
+		}
+		for _, song := range envelope.Data {
+			names = append(names, song.Song)
+		}
+		target = envelope.Links.Next
+		if len(names) > len(ascending) {
+			t.Fatalf("paging does not end: %q", names)
+		}
+	}
+	if strings.Join(names, "|") != strings.Join(ascending, "|") {
+		t.Errorf("songs by release date = %q, want %q", names, ascending)
+	}
+
+	recorder = serve(router, http.MethodGet, "/v2/songs?sort=song&after=bad", "", nil)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid cursor = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"testForWork/internal/models"
+	"testForWork/internal/service"
+)
+
+// labelError answers the errors shared by the genre and tag endpoints.
+func labelError(writer http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrNotFound):
+		http.Error(writer, "Song not found", http.StatusNotFound)
+	default:
+		serverError(writer, action, err)
+	}
+}
+
+// queryList collects a query parameter given several times or comma separated.
+func queryList(router *http.Request, key string) []string {
+	var values []string
+	for _, value := range router.URL.Query()[key] {
+		values = append(values, strings.Split(value, ",")...)
+	}
+	return values
+}
+
+// @Summary Get genres
+// @Description Get all genres with the number of songs of each
+// @Tags labels
+// @Produce json
+// @Success 200 {array} models.Label
+// @Router /genres [get]
+func (handler *Handler) getGenres(writer http.ResponseWriter, router *http.Request) {
+	genres, err := handler.service.GetGenres(router.Context())
+	if err != nil {
+		labelError(writer, "getting genres", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(genres)
+}
+
+// @Summary Get tags
+// @Description Get all tags with the number of songs of each
+// @Tags labels
+// @Produce json
+// @Success 200 {array} models.Label
+// @Router /tags [get]
+func (handler *Handler) getTags(writer http.ResponseWriter, router *http.Request) {
+	tags, err := handler.service.GetTags(router.Context())
+	if err != nil {
+		labelError(writer, "getting tags", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(tags)
+}
+
+// @Summary Add song genres
+// @Description Attach genres to a song, unknown genres are created. Names are lower-cased
+// @Tags labels
+// @Accept json
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param genres body models.LabelsRequest true "Genre names"
+// @Success 200 {object} models.Song
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Song not found"
+// @Router /songs/{id}/genres [post]
+func (handler *Handler) addSongGenres(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	var request models.LabelsRequest
+	if err := json.NewDecoder(router.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request: %s\n", err)
+		http.Error(writer, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	song, err := handler.service.AttachGenres(router.Context(), id, request)
+	if err != nil {
+		labelError(writer, "adding song genres", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(song)
+}
+
+// @Summary Remove song genre
+// @Tags labels
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param name path string true "Genre name"
+// @Success 200 {object} models.Song
+// @Failure 404 {string} string "Song not found"
+// @Router /songs/{id}/genres/{name} [delete]
+func (handler *Handler) deleteSongGenre(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	song, err := handler.service.DetachGenre(router.Context(), id, chi.URLParam(router, "name"))
+	if err != nil {
+		labelError(writer, "removing song genre", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(song)
+}
+
+// @Summary Add song tags
+// @Description Attach free-form tags to a song, unknown tags are created. Names are lower-cased
+// @Tags labels
+// @Accept json
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param tags body models.LabelsRequest true "Tag names"
+// @Success 200 {object} models.Song
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Song not found"
+// @Router /songs/{id}/tags [post]
+func (handler *Handler) addSongTags(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	var request models.LabelsRequest
+	if err := json.NewDecoder(router.Body).Decode(&request); err != nil {
+		log.Printf("Error decoding request: %s\n", err)
+		http.Error(writer, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	song, err := handler.service.AttachTags(router.Context(), id, request)
+	if err != nil {
+		labelError(writer, "adding song tags", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(song)
+}
+
+// @Summary Remove song tag
+// @Tags labels
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param name path string true "Tag name"
+// @Success 200 {object} models.Song
+// @Failure 404 {string} string "Song not found"
+// @Router /songs/{id}/tags/{name} [delete]
+func (handler *Handler) deleteSongTag(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	song, err := handler.service.DetachTag(router.Context(), id, chi.URLParam(router, "name"))
+	if err != nil {
+		labelError(writer, "removing song tag", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(song)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"testForWork/internal/service"
+)
+
+// maxLRCSize bounds the LRC body, a few hundred lines take some kilobytes.
+const maxLRCSize = 1 << 20
+
+// @Summary Get synced lyrics
+// @Description Get the time-synced lyrics of the song as LRC or as JSON with millisecond offsets
+// @Tags lyrics
+// @Produce plain
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param format query string false "Response format" Enums(lrc, json) default(lrc)
+// @Success 200 {object} models.SyncedLyrics
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Song or synced lyrics not found"
+// @Router /songs/{id}/lyrics/synced [get]
+func (handler *Handler) getSyncedLyrics(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	format := router.URL.Query().Get("format")
+	if format != "" && format != "lrc" && format != "json" {
+		http.Error(writer, "format must be lrc or json", http.StatusBadRequest)
+		return
+	}
+
+	lyrics, err := handler.service.GetSyncedLyrics(router.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			http.Error(writer, "Song not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrSyncedLyricsNotFound):
+			http.Error(writer, "Synced lyrics not found", http.StatusNotFound)
+		default:
+			serverError(writer, "getting synced lyrics", err)
+		}
+		return
+	}
+
+	if format == "json" {
+		writer.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writer).Encode(lyrics)
+		return
+	}
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(writer, service.FormatLRC(lyrics))
+}
+
+// @Summary Set synced lyrics
+// @Description Replace the time-synced lyrics of the song with LRC, enhanced LRC word tags are kept. The song text is replaced with the plain text of the lyrics
+// @Tags lyrics
+// @Accept plain
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param lyrics body string true "LRC lyrics"
+// @Param author query string false "Author of the text revision"
+// @Param comment query string false "Comment of the text revision"
+// @Success 200 {object} models.SyncedLyrics
+// @Failure 400 {string} string "Invalid LRC"
+// @Failure 404 {string} string "Song not found"
+// @Failure 413 {string} string "Lyrics too large"
+// @Router /songs/{id}/lyrics/synced [put]
+func (handler *Handler) setSyncedLyrics(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	body, err := io.ReadAll(http.MaxBytesReader(writer, router.Body, maxLRCSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(writer, "Lyrics too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("Error reading request: %s\n", err)
+		http.Error(writer, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	lyrics, err := handler.service.SetSyncedLyrics(router.Context(), id, string(body), revisionNote(router))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, service.ErrNotFound):
+			http.Error(writer, "Song not found", http.StatusNotFound)
+		default:
+			serverError(writer, "setting synced lyrics", err)
+		}
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(lyrics)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Media types of the song text.
+const (
+	mediaJSON   = "application/json"
+	mediaVerses = "application/vnd.music-api.verses+json"
+	mediaPlain  = "text/plain"
+	mediaHTML   = "text/html"
+)
+
+// acceptRange is a media range of an Accept header with its quality.
+type acceptRange struct {
+	kind    string
+	subtype string
+	quality float64
+}
+
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		kind, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok {
+			continue
+		}
+		accepted := acceptRange{kind: strings.TrimSpace(kind), subtype: strings.TrimSpace(subtype), quality: 1}
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if quality, err := strconv.ParseFloat(value, 64); err == nil {
+					accepted.quality = quality
+				}
+			}
+		}
+		ranges = append(ranges, accepted)
+	}
+	return ranges
+}
+
+// quality returns the quality of the media type under the most specific
+// matching range, and -1 when no range matches.
+func quality(ranges []acceptRange, media string) float64 {
+	kind, subtype, _ := strings.Cut(media, "/")
+	best, specificity := -1.0, -1
+	for _, accepted := range ranges {
+		var matched int
+		switch {
+		case accepted.kind == kind && accepted.subtype == subtype:
+			matched = 2
+		case accepted.kind == kind && accepted.subtype == "*":
+			matched = 1
+		case accepted.kind == "*" && accepted.subtype == "*":
+			matched = 0
+		default:
+			continue
+		}
+		if matched > specificity {
+			best, specificity = accepted.quality, matched
+		}
+	}
+	return best
+}
+
+// negotiate picks the offered media type the Accept header of the request
+// prefers, the first offer on a tie or without the header. It returns false
+// when the client accepts none of them.
+func negotiate(accept string, offers []string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+	ranges := parseAccept(accept)
+
+	chosen, best := "", 0.0
+	for _, offer := range offers {
+		if q := quality(ranges, offer); q > best {
+			chosen, best = offer, q
+		}
+	}
+	return chosen, chosen != ""
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		ranges []acceptRange
+	}{
+		{name: "empty", header: ""},
+		{
+			name:   "single",
+			header: "text/plain",
+			ranges: []acceptRange{{kind: "text", subtype: "plain", quality: 1}},
+		},
+		{
+			name:   "qualities and case",
+			header: "Text/HTML;level=1;Q=0.5, application/json , */*;q=0.1",
+			ranges: []acceptRange{
+				{kind: "text", subtype: "html", quality: 0.5},
+				{kind: "application", subtype: "json", quality: 1},
+				{kind: "*", subtype: "*", quality: 0.1},
+			},
+		},
+		{
+			name:   "malformed parts",
+			header: "plain, text/*;q=high, ;q=0",
+			ranges: []acceptRange{{kind: "text", subtype: "*", quality: 1}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ranges := parseAccept(test.header)
+			if !reflect.DeepEqual(ranges, test.ranges) {
+				t.Errorf("parseAccept(%q) = %+v, want %+v", test.header, ranges, test.ranges)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{mediaJSON, mediaVerses, mediaPlain, mediaHTML}
+
+	tests := []struct {
+		name   string
+		accept string
+		offers []string
+		media  string
+		ok     bool
+	}{
+		{name: "no header", accept: "", offers: offers, media: mediaJSON, ok: true},
+		{name: "anything", accept: "*/*", offers: offers, media: mediaJSON, ok: true},
+		{name: "exact", accept: "text/html", offers: offers, media: mediaHTML, ok: true},
+		{name: "vendor type", accept: mediaVerses, offers: offers, media: mediaVerses, ok: true},
+		{name: "subtype wildcard", accept: "text/*", offers: offers, media: mediaPlain, ok: true},
+		{name: "quality", accept: "text/plain;q=0.5, text/html", offers: offers, media: mediaHTML, ok: true},
+		{name: "specific range wins", accept: "text/*;q=0.9, text/plain;q=0.2", offers: offers, media: mediaHTML, ok: true},
+		{name: "excluded by zero quality", accept: "*/*, application/json;q=0", offers: offers, media: mediaVerses, ok: true},
+		{name: "tie keeps the first offer", accept: "text/plain, application/json", offers: offers, media: mediaJSON, ok: true},
+		{name: "unsupported", accept: "image/png", offers: offers, ok: false},
+		{name: "only zero quality", accept: "application/json;q=0", offers: []string{mediaJSON}, ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			media, ok := negotiate(test.accept, test.offers)
+			if media != test.media || ok != test.ok {
+				t.Errorf("negotiate(%q) = %q, %v, want %q, %v", test.accept, media, ok, test.media, test.ok)
+			}
+		})
+	}
+}
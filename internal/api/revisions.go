@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+	"testForWork/internal/models"
+	"testForWork/internal/service"
+)
+
+// revisionNote reads the author and comment of a text change from the query.
+func revisionNote(router *http.Request) models.RevisionNote {
+	return models.RevisionNote{
+		Author:  router.URL.Query().Get("author"),
+		Comment: router.URL.Query().Get("comment"),
+	}
+}
+
+// revisionError answers the errors shared by the revision endpoints.
+func revisionError(writer http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrNotFound):
+		http.Error(writer, "Song not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrRevisionNotFound):
+		http.Error(writer, "Revision not found", http.StatusNotFound)
+	default:
+		serverError(writer, action, err)
+	}
+}
+
+// revisionParam reads a revision number from the query, fallback when absent.
+func revisionParam(router *http.Request, key string, fallback int) (int, error) {
+	raw := router.URL.Query().Get(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(raw)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("%s must be a revision number", key)
+	}
+	return number, nil
+}
+
+// @Summary Get text revisions
+// @Description Get the revisions of the song text, newest first, without their text
+// @Tags revisions
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {array} models.TextRevision
+// @Failure 404 {string} string "Song not found"
+// @Router /songs/{id}/text/revisions [get]
+func (handler *Handler) getRevisions(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	page, _ := strconv.Atoi(router.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(router.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	revisions, err := handler.service.GetRevisions(router.Context(), id, page, limit)
+	if err != nil {
+		revisionError(writer, "getting revisions", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(revisions)
+}
+
+// @Summary Get text revision
+// @Description Get a revision of the song text with the text
+// @Tags revisions
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} models.TextRevision
+// @Failure 404 {string} string "Song or revision not found"
+// @Router /songs/{id}/text/revisions/{rev} [get]
+func (handler *Handler) getRevision(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+	number, _ := strconv.Atoi(chi.URLParam(router, "rev"))
+
+	revision, err := handler.service.GetRevision(router.Context(), id, number)
+	if err != nil {
+		revisionError(writer, "getting revision", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(revision)
+}
+
+// @Summary Diff text revisions
+// @Description Compare two revisions of the song text line by line. Revision 0 is the empty text
+// @Tags revisions
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param from query int false "Older revision, the one before to by default"
+// @Param to query int false "Newer revision, the latest by default"
+// @Success 200 {object} models.TextDiff
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Song or revision not found"
+// @Router /songs/{id}/text/revisions/diff [get]
+func (handler *Handler) diffRevisions(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+
+	from, err := revisionParam(router, "from", -1)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := revisionParam(router, "to", 0)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	diff, err := handler.service.DiffRevisions(router.Context(), id, from, to)
+	if err != nil {
+		revisionError(writer, "diffing revisions", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(diff)
+}
+
+// @Summary Restore text revision
+// @Description Make the text of an earlier revision the song text again, recorded as a new revision
+// @Tags revisions
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param rev path int true "Revision number"
+// @Param author query string false "Author of the change"
+// @Param comment query string false "Comment, restore revision N by default"
+// @Success 200 {object} models.Song
+// @Failure 404 {string} string "Song or revision not found"
+// @Router /songs/{id}/text/revisions/{rev}/restore [post]
+func (handler *Handler) restoreRevision(writer http.ResponseWriter, router *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(router, "id"))
+	number, _ := strconv.Atoi(chi.URLParam(router, "rev"))
+
+	song, err := handler.service.RestoreRevision(router.Context(), id, number, revisionNote(router))
+	if err != nil {
+		revisionError(writer, "restoring revision", err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(song)
+}
@@ -1,6 +1,16 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
 
 type DBConfig struct {
 	Host         string
@@ -10,15 +20,71 @@ type DBConfig struct {
 	DatabaseName string
 }
 
+type DetailsConfig struct {
+	Provider           string
+	Providers          []string
+	Priorities         map[string][]string
+	MusicAPI           string
+	Timeout            time.Duration
+	MaxRetries         int
+	RetryBaseDelay     time.Duration
+	RetryMaxDelay      time.Duration
+	BreakerThreshold   int
+	BreakerOpenTimeout time.Duration
+	Fallback           string
+	CacheSize          int
+	CacheTTL           time.Duration
+	CacheNegativeTTL   time.Duration
+}
+
+type EnrichmentConfig struct {
+	Workers       int
+	QueueSize     int
+	MaxAttempts   int
+	SweepInterval time.Duration
+	// RetryDelay doubles with every failed attempt up to RetryMaxDelay.
+	RetryDelay    time.Duration
+	RetryMaxDelay time.Duration
+}
+
+// TimeoutsConfig bounds each service operation; zero means no limit
+// besides the request context. Operations without a timeout of their own
+// share the one of the closest operation.
+type TimeoutsConfig struct {
+	// GetSongs also bounds counting songs, the songs of a group and reading
+	// a single song.
+	GetSongs    time.Duration
+	SearchSongs time.Duration
+	// GetText also bounds reading sections, synced lyrics and revisions,
+	// diffs of revisions and finding phrases in the text.
+	GetText    time.Duration
+	CreateSong time.Duration
+	// UpdateSong also bounds uploading synced lyrics and restoring a
+	// revision.
+	UpdateSong     time.Duration
+	DeleteSong     time.Duration
+	EnrichSong     time.Duration
+	EnrichSongs    time.Duration
+	Groups         time.Duration
+	Albums         time.Duration
+	Labels         time.Duration
+	ImportSections time.Duration
+}
+
 type Config struct {
-	Port     string
-	DB       DBConfig
-	MusicAPI string
+	Port        string
+	Storage     string
+	DB          DBConfig
+	Details     DetailsConfig
+	Enrichment  EnrichmentConfig
+	DateLayouts []string
+	Timeouts    TimeoutsConfig
 }
 
 func LoadConfig() *Config {
 	return &Config{
-		Port: getEnv("PORT", "8080"),
+		Port:    getEnv("PORT", "8080"),
+		Storage: getEnv("STORAGE", StoragePostgres),
 		DB: DBConfig{
 			Host:         getEnv("DB_HOST", "localhost"),
 			Port:         getEnv("DB_PORT", "5432"),
@@ -26,7 +92,50 @@ func LoadConfig() *Config {
 			Password:     getEnv("DB_PASSWORD", "admin"),
 			DatabaseName: getEnv("DB_NAME", "music_db"),
 		},
-		MusicAPI: getEnv("MUSIC_API", "http://localhost:8080"),
+		Details: DetailsConfig{
+			Provider:  getEnv("DETAILS_PROVIDER", "local"),
+			Providers: getListEnv("DETAILS_PROVIDERS"),
+			Priorities: getPrioritiesEnv(map[string]string{
+				"release_date": "DETAILS_PRIORITY_RELEASE_DATE",
+				"text":         "DETAILS_PRIORITY_TEXT",
+				"link":         "DETAILS_PRIORITY_LINK",
+			}),
+			MusicAPI: getEnv("MUSIC_API", "http://localhost:8081"),
+			Timeout:  getDurationEnv("MUSIC_API_TIMEOUT", 10*time.Second),
+
+			MaxRetries:         getIntEnv("MUSIC_API_MAX_RETRIES", 3),
+			RetryBaseDelay:     getDurationEnv("MUSIC_API_RETRY_BASE_DELAY", 200*time.Millisecond),
+			RetryMaxDelay:      getDurationEnv("MUSIC_API_RETRY_MAX_DELAY", 5*time.Second),
+			BreakerThreshold:   getIntEnv("MUSIC_API_BREAKER_THRESHOLD", 5),
+			BreakerOpenTimeout: getDurationEnv("MUSIC_API_BREAKER_OPEN_TIMEOUT", 30*time.Second),
+			Fallback:           getEnv("MUSIC_API_FALLBACK", ""),
+			CacheSize:          getIntEnv("DETAILS_CACHE_SIZE", 1000),
+			CacheTTL:           getDurationEnv("DETAILS_CACHE_TTL", time.Hour),
+			CacheNegativeTTL:   getDurationEnv("DETAILS_CACHE_NEGATIVE_TTL", 5*time.Minute),
+		},
+		Enrichment: EnrichmentConfig{
+			Workers:       getIntEnv("ENRICHMENT_WORKERS", 4),
+			QueueSize:     getIntEnv("ENRICHMENT_QUEUE_SIZE", 100),
+			MaxAttempts:   getIntEnv("ENRICHMENT_MAX_ATTEMPTS", 5),
+			SweepInterval: getDurationEnv("ENRICHMENT_SWEEP_INTERVAL", 30*time.Second),
+			RetryDelay:    getDurationEnv("ENRICHMENT_RETRY_DELAY", time.Minute),
+			RetryMaxDelay: getDurationEnv("ENRICHMENT_RETRY_MAX_DELAY", time.Hour),
+		},
+		DateLayouts: getListEnv("RELEASE_DATE_LAYOUTS"),
+		Timeouts: TimeoutsConfig{
+			GetSongs:       getDurationEnv("TIMEOUT_GET_SONGS", 5*time.Second),
+			SearchSongs:    getDurationEnv("TIMEOUT_SEARCH_SONGS", 5*time.Second),
+			GetText:        getDurationEnv("TIMEOUT_GET_TEXT", 5*time.Second),
+			CreateSong:     getDurationEnv("TIMEOUT_CREATE_SONG", 5*time.Second),
+			UpdateSong:     getDurationEnv("TIMEOUT_UPDATE_SONG", 5*time.Second),
+			DeleteSong:     getDurationEnv("TIMEOUT_DELETE_SONG", 5*time.Second),
+			EnrichSong:     getDurationEnv("TIMEOUT_ENRICH_SONG", 30*time.Second),
+			EnrichSongs:    getDurationEnv("TIMEOUT_ENRICH_SONGS", 5*time.Minute),
+			Groups:         getDurationEnv("TIMEOUT_GROUPS", 5*time.Second),
+			Albums:         getDurationEnv("TIMEOUT_ALBUMS", 5*time.Second),
+			Labels:         getDurationEnv("TIMEOUT_LABELS", 5*time.Second),
+			ImportSections: getDurationEnv("TIMEOUT_IMPORT_SECTIONS", 5*time.Minute),
+		},
 	}
 }
 
@@ -36,3 +145,38 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getListEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getPrioritiesEnv reads a provider list per details field.
+func getPrioritiesEnv(keys map[string]string) map[string][]string {
+	priorities := make(map[string][]string)
+	for field, key := range keys {
+		if values := getListEnv(key); len(values) > 0 {
+			priorities[field] = values
+		}
+	}
+	return priorities
+}
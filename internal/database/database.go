@@ -73,7 +73,7 @@ func createDatabaseIfNotExists(cfg config.DBConfig) error {
 		if err != nil {
 			log.Fatalf("failed on creating database: %v", err)
 		}
-		log.Printf("database successfully created", cfg.DatabaseName)
+		log.Printf("database %s successfully created", cfg.DatabaseName)
 	}
 	return nil
 }
@@ -0,0 +1,7 @@
+-- migrations/000002_nullable_release_date.up.sql
+-- +goose Up
+ALTER TABLE songs ALTER COLUMN release_date DROP NOT NULL;
+
+-- +goose Down
+UPDATE songs SET release_date = created_at::date WHERE release_date IS NULL;
+ALTER TABLE songs ALTER COLUMN release_date SET NOT NULL;
@@ -0,0 +1,21 @@
+-- migrations/000003_enrichment_status.up.sql
+-- +goose Up
+ALTER TABLE songs
+    ADD COLUMN IF NOT EXISTS enrichment_status TEXT NOT NULL DEFAULT 'ok',
+    ADD COLUMN IF NOT EXISTS enrichment_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS enrichment_error TEXT NOT NULL DEFAULT '';
+
+-- songs stored before this migration were enriched synchronously
+ALTER TABLE songs ALTER COLUMN enrichment_status SET DEFAULT 'pending';
+ALTER TABLE songs ADD CONSTRAINT songs_enrichment_status_check
+    CHECK (enrichment_status IN ('pending', 'ok', 'failed'));
+
+CREATE INDEX IF NOT EXISTS idx_songs_enrichment_pending ON songs(id) WHERE enrichment_status = 'pending';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_songs_enrichment_pending;
+ALTER TABLE songs
+    DROP CONSTRAINT IF EXISTS songs_enrichment_status_check,
+    DROP COLUMN IF EXISTS enrichment_error,
+    DROP COLUMN IF EXISTS enrichment_attempts,
+    DROP COLUMN IF EXISTS enrichment_status;
@@ -0,0 +1,7 @@
+-- migrations/000004_details_sources.up.sql
+-- +goose Up
+-- maps release_date/text/link to the name of the provider that supplied it
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS details_sources JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE songs DROP COLUMN IF EXISTS details_sources;
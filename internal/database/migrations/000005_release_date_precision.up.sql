@@ -0,0 +1,14 @@
+-- migrations/000005_release_date_precision.up.sql
+-- +goose Up
+-- release_date holds the start of the period: 2006-01-01 with 'year' precision means "2006"
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS release_date_precision TEXT NOT NULL DEFAULT 'day';
+ALTER TABLE songs ADD CONSTRAINT songs_release_date_precision_check
+    CHECK (release_date_precision IN ('day', 'month', 'year'));
+
+CREATE INDEX IF NOT EXISTS idx_songs_release_date ON songs(release_date, release_date_precision);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_songs_release_date;
+ALTER TABLE songs
+    DROP CONSTRAINT IF EXISTS songs_release_date_precision_check,
+    DROP COLUMN IF EXISTS release_date_precision;
@@ -0,0 +1,42 @@
+-- migrations/000006_groups.up.sql
+-- +goose Up
+CREATE TABLE IF NOT EXISTS groups (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    formed_year INT,
+    country TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- "Muse", "muse" and "MUSE " are the same group
+CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_name ON groups ((LOWER(TRIM(name))));
+
+-- the spelling of the oldest song wins
+INSERT INTO groups (name)
+SELECT DISTINCT ON (LOWER(TRIM(group_name))) TRIM(group_name)
+FROM songs
+ORDER BY LOWER(TRIM(group_name)), id;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS group_id INT REFERENCES groups(id);
+UPDATE songs SET group_id = groups.id
+FROM groups
+WHERE LOWER(TRIM(groups.name)) = LOWER(TRIM(songs.group_name));
+ALTER TABLE songs ALTER COLUMN group_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_songs_group_id ON songs(group_id);
+DROP INDEX IF EXISTS idx_songs_group;
+ALTER TABLE songs DROP COLUMN IF EXISTS group_name;
+
+-- +goose Down
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS group_name TEXT NOT NULL DEFAULT '';
+UPDATE songs SET group_name = groups.name
+FROM groups
+WHERE groups.id = songs.group_id;
+ALTER TABLE songs ALTER COLUMN group_name DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_songs_group ON songs(group_name);
+
+DROP INDEX IF EXISTS idx_songs_group_id;
+ALTER TABLE songs DROP COLUMN IF EXISTS group_id;
+DROP TABLE IF EXISTS groups;
@@ -0,0 +1,33 @@
+-- migrations/000007_albums.up.sql
+-- +goose Up
+CREATE TABLE IF NOT EXISTS albums (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES groups(id),
+    title TEXT NOT NULL,
+    release_date DATE,
+    release_date_precision TEXT NOT NULL DEFAULT 'day',
+    album_type TEXT NOT NULL DEFAULT 'lp',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT albums_release_date_precision_check CHECK (release_date_precision IN ('day', 'month', 'year')),
+    CONSTRAINT albums_album_type_check CHECK (album_type IN ('lp', 'ep', 'single', 'compilation'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_albums_group_id ON albums(group_id);
+
+ALTER TABLE songs
+    ADD COLUMN IF NOT EXISTS album_id INT REFERENCES albums(id),
+    ADD COLUMN IF NOT EXISTS track_number INT;
+ALTER TABLE songs ADD CONSTRAINT songs_track_number_check
+    CHECK (track_number IS NULL OR (album_id IS NOT NULL AND track_number > 0));
+
+-- one song per position on an album
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_album_track ON songs(album_id, track_number) WHERE album_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_songs_album_track;
+ALTER TABLE songs
+    DROP CONSTRAINT IF EXISTS songs_track_number_check,
+    DROP COLUMN IF EXISTS track_number,
+    DROP COLUMN IF EXISTS album_id;
+DROP TABLE IF EXISTS albums;
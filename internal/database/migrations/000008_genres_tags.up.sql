@@ -0,0 +1,34 @@
+-- migrations/000008_genres_tags.up.sql
+-- +goose Up
+-- names are stored normalized: lower case, single spaces
+CREATE TABLE IF NOT EXISTS genres (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS song_genres (
+    song_id INT NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    genre_id INT NOT NULL REFERENCES genres(id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, genre_id)
+);
+
+CREATE TABLE IF NOT EXISTS song_tags (
+    song_id INT NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, tag_id)
+);
+
+-- the primary keys serve lookups by song, these serve filtering by genre or tag
+CREATE INDEX IF NOT EXISTS idx_song_genres_genre_id ON song_genres(genre_id);
+CREATE INDEX IF NOT EXISTS idx_song_tags_tag_id ON song_tags(tag_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS song_tags;
+DROP TABLE IF EXISTS song_genres;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS genres;
@@ -0,0 +1,58 @@
+-- migrations/000009_song_search.up.sql
+-- +goose Up
+-- The vector covers the group name as well, which lives in another table, so
+-- it is kept up to date by triggers instead of being a generated column.
+-- Every part is indexed with both the english and the russian configuration.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION song_search_vector(song_name TEXT, group_name TEXT, lyrics TEXT) RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('english', COALESCE(song_name, '')) || to_tsvector('russian', COALESCE(song_name, '')), 'A')
+        || setweight(to_tsvector('english', COALESCE(group_name, '')) || to_tsvector('russian', COALESCE(group_name, '')), 'B')
+        || setweight(to_tsvector('english', COALESCE(lyrics, '')) || to_tsvector('russian', COALESCE(lyrics, '')), 'C')
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION songs_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := song_search_vector(
+        NEW.song_name, (SELECT name FROM groups WHERE id = NEW.group_id), NEW.text
+    );
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER songs_search_vector_update
+    BEFORE INSERT OR UPDATE OF song_name, group_id, text ON songs
+    FOR EACH ROW EXECUTE FUNCTION songs_search_vector_update();
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION groups_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    UPDATE songs SET search_vector = song_search_vector(song_name, NEW.name, text)
+    WHERE group_id = NEW.id;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER groups_search_vector_update
+    AFTER UPDATE OF name ON groups
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION groups_search_vector_update();
+
+UPDATE songs s SET search_vector = song_search_vector(s.song_name, g.name, s.text)
+FROM groups g WHERE g.id = s.group_id;
+
+CREATE INDEX IF NOT EXISTS idx_songs_search_vector ON songs USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_songs_search_vector;
+DROP TRIGGER IF EXISTS groups_search_vector_update ON groups;
+DROP FUNCTION IF EXISTS groups_search_vector_update();
+DROP TRIGGER IF EXISTS songs_search_vector_update ON songs;
+DROP FUNCTION IF EXISTS songs_search_vector_update();
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS song_search_vector(TEXT, TEXT, TEXT);
@@ -0,0 +1,16 @@
+-- migrations/000010_name_matching.up.sql
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- exact song name filter ignores case
+CREATE INDEX IF NOT EXISTS idx_songs_song_name_lower ON songs(LOWER(song_name));
+
+-- trigram indexes serve ILIKE in the prefix and contains modes
+CREATE INDEX IF NOT EXISTS idx_songs_song_name_trgm ON songs USING GIN (song_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_groups_name_trgm ON groups USING GIN (name gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_groups_name_trgm;
+DROP INDEX IF EXISTS idx_songs_song_name_trgm;
+DROP INDEX IF EXISTS idx_songs_song_name_lower;
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,13 @@
+-- migrations/000011_song_sort_indexes.up.sql
+-- +goose Up
+-- the id closes every sort so pages are stable, the indexes carry it as well
+CREATE INDEX IF NOT EXISTS idx_songs_release_date_id ON songs(release_date, id);
+CREATE INDEX IF NOT EXISTS idx_songs_created_at_id ON songs(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_songs_song_name_lower_id ON songs(LOWER(song_name), id);
+DROP INDEX IF EXISTS idx_songs_song_name_lower;
+
+-- +goose Down
+CREATE INDEX IF NOT EXISTS idx_songs_song_name_lower ON songs(LOWER(song_name));
+DROP INDEX IF EXISTS idx_songs_song_name_lower_id;
+DROP INDEX IF EXISTS idx_songs_created_at_id;
+DROP INDEX IF EXISTS idx_songs_release_date_id;
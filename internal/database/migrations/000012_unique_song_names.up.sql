@@ -0,0 +1,30 @@
+-- migrations/000012_unique_song_names.up.sql
+-- +goose Up
+-- Existing duplicates are not merged automatically: the migration stops and
+-- lists them, and is applied on the next start once they are resolved.
+-- +goose StatementBegin
+DO $$
+DECLARE
+    report TEXT;
+BEGIN
+    SELECT string_agg(format('%s - %s: ids %s', d.group_name, d.song_name, d.ids), E'\n' ORDER BY d.group_name, d.song_name)
+    INTO report
+    FROM (
+        SELECT g.name AS group_name, MIN(s.song_name) AS song_name, array_agg(s.id ORDER BY s.id) AS ids
+        FROM songs s
+        JOIN groups g ON g.id = s.group_id
+        GROUP BY g.name, s.group_id, LOWER(TRIM(s.song_name))
+        HAVING COUNT(*) > 1
+    ) d;
+
+    IF report IS NOT NULL THEN
+        RAISE EXCEPTION E'songs with the same group and name exist, merge or delete them first:\n%', report;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_songs_group_song_name ON songs(group_id, LOWER(TRIM(song_name)));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_songs_group_song_name;
@@ -0,0 +1,21 @@
+-- migrations/000013_song_sections.up.sql
+-- +goose Up
+-- lyrics split into typed sections; a repeated section has no lines of its own
+-- and points at the earlier section it repeats. Sections of songs stored
+-- before this table existed are parsed on first read or by
+-- POST /admin/sections/import, SQL cannot parse the section markers.
+CREATE TABLE IF NOT EXISTS song_sections (
+    song_id INT NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    type TEXT NOT NULL,
+    ordinal INT NOT NULL,
+    label TEXT NOT NULL DEFAULT '',
+    lines TEXT[],
+    repeat_of INT,
+    PRIMARY KEY (song_id, position),
+    FOREIGN KEY (song_id, repeat_of) REFERENCES song_sections(song_id, position) ON DELETE CASCADE,
+    CHECK ((lines IS NULL) <> (repeat_of IS NULL))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS song_sections;
@@ -0,0 +1,13 @@
+-- migrations/000014_synced_lyrics.up.sql
+-- +goose Up
+-- time-synced lyrics imported from LRC; songs.text holds the plain text
+-- derived from them. lines is an array of {time_ms, text, words}.
+CREATE TABLE IF NOT EXISTS song_synced_lyrics (
+    song_id INT PRIMARY KEY REFERENCES songs(id) ON DELETE CASCADE,
+    tags JSONB NOT NULL DEFAULT '{}',
+    lines JSONB NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS song_synced_lyrics;
@@ -0,0 +1,23 @@
+-- migrations/000015_text_revisions.up.sql
+-- +goose Up
+-- every change of songs.text is recorded as a numbered revision of the song
+CREATE TABLE IF NOT EXISTS song_text_revisions (
+    song_id INT NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    text TEXT NOT NULL,
+    author TEXT NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (song_id, revision)
+);
+
+-- the current text of existing songs becomes their first revision, so the
+-- next change can be diffed and rolled back
+INSERT INTO song_text_revisions (song_id, revision, text, comment, created_at)
+SELECT id, 1, text, 'text before revision history', updated_at
+FROM songs
+WHERE text <> ''
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS song_text_revisions;
@@ -0,0 +1,8 @@
+-- migrations/000016_enrichment_retry.up.sql
+-- +goose Up
+-- a pending song whose enrichment failed is picked up again only once this
+-- time has passed
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS enrichment_retry_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE songs DROP COLUMN IF EXISTS enrichment_retry_at;
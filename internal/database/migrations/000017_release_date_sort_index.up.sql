@@ -0,0 +1,11 @@
+-- migrations/000017_release_date_sort_index.up.sql
+-- +goose Up
+-- a backward scan of idx_songs_release_date_id puts songs without a date
+-- first, the newest first sort keeps them last and needs its own index
+CREATE INDEX IF NOT EXISTS idx_songs_release_date_desc_id ON songs(release_date DESC NULLS LAST, id);
+-- idx_songs_release_date_id serves the release date filters as well
+DROP INDEX IF EXISTS idx_songs_release_date;
+
+-- +goose Down
+CREATE INDEX IF NOT EXISTS idx_songs_release_date ON songs(release_date, release_date_precision);
+DROP INDEX IF EXISTS idx_songs_release_date_desc_id;
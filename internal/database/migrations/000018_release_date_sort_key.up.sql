@@ -0,0 +1,21 @@
+-- migrations/000018_release_date_sort_key.up.sql
+-- +goose Up
+-- songs are sorted by the end of their release period, so "2006" follows
+-- the dates within 2006; of periods ending together the finer comes first
+-- (the seconds only break that tie). Keep in sync with ReleaseDate.SortKey.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS release_date_sort TIMESTAMP GENERATED ALWAYS AS (
+    release_date + CASE release_date_precision
+        WHEN 'year' THEN INTERVAL '1 year 2 seconds'
+        WHEN 'month' THEN INTERVAL '1 month 1 second'
+        ELSE INTERVAL '1 day' END
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_songs_release_date_sort_id ON songs(release_date_sort, id);
+CREATE INDEX IF NOT EXISTS idx_songs_release_date_sort_desc_id ON songs(release_date_sort DESC NULLS LAST, id);
+DROP INDEX IF EXISTS idx_songs_release_date_desc_id;
+
+-- +goose Down
+CREATE INDEX IF NOT EXISTS idx_songs_release_date_desc_id ON songs(release_date DESC NULLS LAST, id);
+DROP INDEX IF EXISTS idx_songs_release_date_sort_desc_id;
+DROP INDEX IF EXISTS idx_songs_release_date_sort_id;
+ALTER TABLE songs DROP COLUMN IF EXISTS release_date_sort;
@@ -1,27 +1,473 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 type Song struct {
+	ID          int      `json:"id"`
+	GroupID     int      `json:"group_id"`
+	Group       string   `json:"group"`
+	Song        string   `json:"song"`
+	AlbumID     *int     `json:"album_id"`
+	Album       string   `json:"album,omitempty"`
+	TrackNumber *int     `json:"track_number"`
+	Genres      []string `json:"genres"`
+	Tags        []string `json:"tags"`
+	// Score is the similarity to the group and song filters, set only when
+	// they are matched fuzzily.
+	Score              *float64     `json:"score,omitempty"`
+	ReleaseDate        *ReleaseDate `json:"release_date" swaggertype:"string" example:"2006-07-16"`
+	Link               string       `json:"link"`
+	Text               string       `json:"text"`
+	EnrichmentStatus   string       `json:"enrichment_status"`
+	EnrichmentAttempts int          `json:"enrichment_attempts"`
+	EnrichmentError    string       `json:"enrichment_error,omitempty"`
+	// EnrichmentRetryAt is when a pending song is tried again after a
+	// failed attempt.
+	EnrichmentRetryAt *time.Time `json:"enrichment_retry_at,omitempty"`
+	Sources           Sources    `json:"sources"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+const (
+	PrecisionDay   = "day"
+	PrecisionMonth = "month"
+	PrecisionYear  = "year"
+)
+
+// ReleaseDate is a date known to a day, a month or only a year. Time holds
+// the start of the period.
+type ReleaseDate struct {
+	Time      time.Time
+	Precision string
+}
+
+func (date ReleaseDate) String() string {
+	switch date.Precision {
+	case PrecisionYear:
+		return date.Time.Format("2006")
+	case PrecisionMonth:
+		return date.Time.Format("2006-01")
+	default:
+		return date.Time.Format("2006-01-02")
+	}
+}
+
+// SortKey orders release dates by the end of their period, so a date known
+// only to the year or the month follows the dates within it; of periods
+// ending the same day the finer comes first. Matches the release_date_sort
+// column in Postgres.
+func (date ReleaseDate) SortKey() time.Time {
+	year, month, day := date.Time.Date()
+	start := ReleaseDate{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Precision: date.Precision}
+	switch date.Precision {
+	case PrecisionYear:
+		return start.End().Add(2 * time.Second)
+	case PrecisionMonth:
+		return start.End().Add(time.Second)
+	default:
+		return start.End()
+	}
+}
+
+// End returns the first day after the period.
+func (date ReleaseDate) End() time.Time {
+	switch date.Precision {
+	case PrecisionYear:
+		return date.Time.AddDate(1, 0, 0)
+	case PrecisionMonth:
+		return date.Time.AddDate(0, 1, 0)
+	default:
+		return date.Time.AddDate(0, 0, 1)
+	}
+}
+
+func (date ReleaseDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(date.String())
+}
+
+const (
+	EnrichmentPending = "pending"
+	EnrichmentOK      = "ok"
+	EnrichmentFailed  = "failed"
+)
+
+type Group struct {
 	ID          int       `json:"id"`
-	Group       string    `json:"group"`
-	Song        string    `json:"song"`
-	ReleaseDate time.Time `json:"release_date"`
-	Link        string    `json:"link"`
-	Text        string    `json:"text"`
+	Name        string    `json:"name"`
+	FormedYear  *int      `json:"formed_year" example:"1994"`
+	Country     string    `json:"country"`
+	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+type GroupRequest struct {
+	Name        string `json:"name"`
+	FormedYear  *int   `json:"formed_year,omitempty"`
+	Country     string `json:"country"`
+	Description string `json:"description"`
+}
+
+type GroupUpdateRequest struct {
+	Name        *string `json:"name,omitempty"`
+	FormedYear  *int    `json:"formed_year,omitempty"`
+	Country     *string `json:"country,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+const (
+	AlbumLP          = "lp"
+	AlbumEP          = "ep"
+	AlbumSingle      = "single"
+	AlbumCompilation = "compilation"
+)
+
+// AlbumTypes lists the accepted album types.
+var AlbumTypes = []string{AlbumLP, AlbumEP, AlbumSingle, AlbumCompilation}
+
+type Album struct {
+	ID          int          `json:"id"`
+	GroupID     int          `json:"group_id"`
+	Group       string       `json:"group"`
+	Title       string       `json:"title"`
+	ReleaseDate *ReleaseDate `json:"release_date" swaggertype:"string" example:"2006-06-19"`
+	Type        string       `json:"type" enums:"lp,ep,single,compilation"`
+	TrackCount  int          `json:"track_count"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type AlbumRequest struct {
+	Group       string `json:"group"`
+	Title       string `json:"title"`
+	ReleaseDate string `json:"release_date,omitempty"`
+	Type        string `json:"type,omitempty" enums:"lp,ep,single,compilation"`
+}
+
+type AlbumUpdateRequest struct {
+	Group       *string `json:"group,omitempty"`
+	Title       *string `json:"title,omitempty"`
+	ReleaseDate *string `json:"release_date,omitempty"`
+	Type        *string `json:"type,omitempty" enums:"lp,ep,single,compilation"`
+}
+
+// AlbumTracksRequest is the full tracklist in order, the first song is track 1.
+type AlbumTracksRequest struct {
+	SongIDs []int `json:"song_ids"`
+}
+
+// Label is a genre or a tag with the number of songs carrying it.
+type Label struct {
+	Name      string `json:"name"`
+	SongCount int    `json:"song_count"`
+}
+
+type LabelsRequest struct {
+	Names []string `json:"names"`
+}
+
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+// Match modes of the group and song name filters, all ignore case.
+const (
+	NameMatchExact    = "exact"
+	NameMatchPrefix   = "prefix"
+	NameMatchContains = "contains"
+	NameMatchFuzzy    = "fuzzy"
+)
+
+var NameMatchModes = []string{NameMatchExact, NameMatchPrefix, NameMatchContains, NameMatchFuzzy}
+
+// Fields songs can be sorted by.
+const (
+	SortID          = "id"
+	SortSong        = "song"
+	SortGroup       = "group"
+	SortReleaseDate = "release_date"
+	SortTrackNumber = "track_number"
+	SortCreatedAt   = "created_at"
+	SortUpdatedAt   = "updated_at"
+)
+
+var SongSortFields = []string{SortID, SortSong, SortGroup, SortReleaseDate, SortTrackNumber, SortCreatedAt, SortUpdatedAt}
+
+type SongFilter struct {
+	GroupID  int
+	AlbumID  int
+	Group    string
+	Song     string
+	Released string
+	// ReleasedFrom and ReleasedTo bound the release date, both inclusive,
+	// in any release date layout: ReleasedTo "2000" takes in all of 2000.
+	ReleasedFrom string
+	ReleasedTo   string
+	// CreatedFrom, CreatedTo and UpdatedSince take an RFC 3339 time or a
+	// date in a release date layout.
+	CreatedFrom  string
+	CreatedTo    string
+	UpdatedSince string
+	// HasLink and HasText are "true" or "false", empty to ignore.
+	HasLink string
+	HasText string
+	// NameMatch is how Group and Song are compared, one of NameMatchModes.
+	NameMatch string
+	Genres    []string
+	Tags      []string
+	// Sort is a comma separated list of SongSortFields, a field prefixed
+	// with - sorts descending.
+	Sort string
+	// Match is MatchAll to require every genre and tag, MatchAny for at
+	// least one of them.
+	Match string
+	// After and Before are cursors of a previous page, they replace the
+	// page number.
+	After  string
+	Before string
+}
+
+// SongPage is a page of songs with the cursors of its neighbours, empty
+// when there is no such page.
+type SongPage struct {
+	Songs      []Song
+	NextCursor string
+	PrevCursor string
+}
+
+// PageLinks are links to a page and its neighbours, empty when there is no
+// such page.
+type PageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// PageInfo describes a page of a paginated response. Page is left out for
+// pages found by cursor.
+type PageInfo struct {
+	Page       int       `json:"page,omitempty"`
+	Limit      int       `json:"limit"`
+	Total      int       `json:"total"`
+	TotalPages int       `json:"total_pages"`
+	Links      PageLinks `json:"links"`
+}
+
+type SongsEnvelope struct {
+	Data []Song `json:"data"`
+	PageInfo
+}
+
+// Verse is a verse of the song text with its number, counted from 0 like
+// the verses of TextMatch.
+type Verse struct {
+	Verse int    `json:"verse"`
+	Text  string `json:"text"`
+}
+
+type TextEnvelope struct {
+	Data []string `json:"data"`
+	PageInfo
+}
+
+// SongSearchResult is a song found by a text search. Snippet is the best
+// matching verse with the matched words wrapped in <b></b>.
+type SongSearchResult struct {
+	Song
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
 type SongRequest struct {
 	Group string `json:"group"`
 	Song  string `json:"song"`
 }
 
+// What POST /songs does when the group already has a song of that name.
+const (
+	OnConflictError  = "error"
+	OnConflictReturn = "return"
+	OnConflictUpdate = "update"
+)
+
+var OnConflictModes = []string{OnConflictError, OnConflictReturn, OnConflictUpdate}
+
+// Types of lyrics sections.
+const (
+	SectionVerse     = "verse"
+	SectionPreChorus = "pre-chorus"
+	SectionChorus    = "chorus"
+	SectionBridge    = "bridge"
+	SectionIntro     = "intro"
+	SectionOutro     = "outro"
+	SectionHook      = "hook"
+	SectionOther     = "other"
+)
+
+var SectionTypes = []string{
+	SectionVerse, SectionPreChorus, SectionChorus, SectionBridge,
+	SectionIntro, SectionOutro, SectionHook, SectionOther,
+}
+
+// Section is a part of the song lyrics. Position numbers the sections of a
+// song from 1, Ordinal numbers the sections of one type, so the second
+// chorus has the ordinal 2. A section repeating an earlier one is stored
+// without lines and points at it with RepeatOf; the API fills in the lines.
+type Section struct {
+	Position int      `json:"position"`
+	Type     string   `json:"type"`
+	Ordinal  int      `json:"ordinal"`
+	Label    string   `json:"label,omitempty"`
+	Lines    []string `json:"lines"`
+	RepeatOf *int     `json:"repeat_of,omitempty"`
+}
+
+type SectionImportResult struct {
+	Imported int `json:"imported"`
+}
+
+// SyncedLyrics are lyrics with the time each line starts at, in
+// milliseconds from the start of the song, as imported from LRC. Tags hold
+// the LRC metadata like ar (artist) and ti (title).
+type SyncedLyrics struct {
+	Tags  map[string]string `json:"tags,omitempty"`
+	Lines []SyncedLine      `json:"lines"`
+}
+
+// SyncedLine is a line of synced lyrics; Words are set only for enhanced
+// LRC with a time tag per word. A line without text marks a pause.
+type SyncedLine struct {
+	TimeMs int          `json:"time_ms"`
+	Text   string       `json:"text"`
+	Words  []SyncedWord `json:"words,omitempty"`
+}
+
+type SyncedWord struct {
+	TimeMs int    `json:"time_ms"`
+	Text   string `json:"text"`
+}
+
+// TextRevision is a version of the song text. Revisions are numbered per
+// song from 1, a new one is recorded on every change of the text. Lists of
+// revisions leave the text out.
+type TextRevision struct {
+	Revision  int       `json:"revision"`
+	Text      *string   `json:"text,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RevisionNote describes a change of the text for its revision.
+type RevisionNote struct {
+	Author  string
+	Comment string
+}
+
+// Operations of a line diff.
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffLine is a line of a diff. OldLine and NewLine number the line in the
+// older and the newer text from 1, zero when it is not there.
+type DiffLine struct {
+	Op      string `json:"op" enums:"equal,insert,delete"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+// TextFind is a search for a phrase in the song text. CaseSensitive and
+// WholeWord are "true" or "false", empty for false.
+type TextFind struct {
+	Query         string
+	CaseSensitive string
+	WholeWord     string
+}
+
+// TextMatch is an occurrence of a phrase in the song text. Verse counts the
+// verses of GET /songs/{id}/text from 0 and Line the lines of the verse;
+// Start and End are character offsets in the line, End excluded. Page is
+// the text page holding the verse for the requested limit.
+type TextMatch struct {
+	Verse int    `json:"verse"`
+	Line  int    `json:"line"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Page  int    `json:"page"`
+	Text  string `json:"text"`
+}
+
+type TextDiff struct {
+	From    int        `json:"from"`
+	To      int        `json:"to"`
+	Added   int        `json:"added"`
+	Removed int        `json:"removed"`
+	Lines   []DiffLine `json:"lines"`
+}
+
 type SongDetail struct {
-	ReleaseDate string `json:"release_date"`
-	Text        string `json:"text"`
-	Link        string `json:"link"`
+	ReleaseDate string  `json:"release_date"`
+	Text        string  `json:"text"`
+	Link        string  `json:"link"`
+	Sources     Sources `json:"sources,omitempty"`
+	// Fallback marks details served by a fallback provider while the
+	// upstream was unavailable; they are never cached.
+	Fallback bool `json:"-"`
+}
+
+const (
+	FieldReleaseDate = "release_date"
+	FieldText        = "text"
+	FieldLink        = "link"
+)
+
+// DetailFields lists the song fields filled by details providers.
+var DetailFields = []string{FieldReleaseDate, FieldText, FieldLink}
+
+// Sources maps a detail field to the name of the provider that supplied it.
+type Sources map[string]string
+
+func (sources Sources) Value() (driver.Value, error) {
+	if sources == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(sources)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (sources *Sources) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*sources = Sources{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Sources", value)
+	}
+	result := Sources{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	*sources = result
+	return nil
 }
 
 type SongUpdateRequest struct {
@@ -30,4 +476,61 @@ type SongUpdateRequest struct {
 	ReleaseDate *string `json:"release_date,omitempty"`
 	Text        *string `json:"text,omitempty"`
 	Link        *string `json:"link,omitempty"`
+	// Author and Comment describe the text revision recorded when Text
+	// changes the text.
+	Author  string `json:"author,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type FieldChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+	Source string `json:"source,omitempty"`
+}
+
+type EnrichmentResult struct {
+	ID      int                    `json:"id"`
+	Changes map[string]FieldChange `json:"changes"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+type CircuitBreakerStatus struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Threshold           int        `json:"threshold"`
+	Trips               int        `json:"trips"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+type DetailsCacheStats struct {
+	Size         int     `json:"size"`
+	Capacity     int     `json:"capacity"`
+	TTL          string  `json:"ttl"`
+	NegativeTTL  string  `json:"negative_ttl"`
+	Hits         uint64  `json:"hits"`
+	NegativeHits uint64  `json:"negative_hits"`
+	Misses       uint64  `json:"misses"`
+	Evictions    uint64  `json:"evictions"`
+	HitRatio     float64 `json:"hit_ratio"`
+}
+
+type DetailsCacheEntry struct {
+	Group     string      `json:"group"`
+	Song      string      `json:"song"`
+	NotFound  bool        `json:"not_found"`
+	Detail    *SongDetail `json:"detail,omitempty"`
+	StoredAt  time.Time   `json:"stored_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+type DetailsCache struct {
+	Stats   *DetailsCacheStats  `json:"stats"`
+	Entries []DetailsCacheEntry `json:"entries"`
+}
+
+type CachePurgeResult struct {
+	Purged int `json:"purged"`
 }
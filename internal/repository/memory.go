@@ -0,0 +1,496 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"testForWork/internal/models"
+	"time"
+)
+
+// memoryStore keeps everything in maps behind one lock, so the repositories
+// can check references between each other like foreign keys do. It behaves
+// like the Postgres repositories and is meant for tests and running without
+// a database.
+type memoryStore struct {
+	mu          sync.RWMutex
+	songs       map[int]*models.Song
+	groups      map[int]*models.Group
+	albums      map[int]*models.Album
+	labels      map[string]map[string]bool
+	sections    map[int][]models.Section
+	synced      map[int]*models.SyncedLyrics
+	revisions   map[int][]models.TextRevision
+	nextSongID  int
+	nextGroupID int
+	nextAlbumID int
+}
+
+type MemorySongRepository struct {
+	*memoryStore
+}
+
+type MemoryGroupRepository struct {
+	*memoryStore
+}
+
+type MemoryAlbumRepository struct {
+	*memoryStore
+}
+
+type MemoryLabelRepository struct {
+	*memoryStore
+	kind string
+}
+
+type MemorySectionRepository struct {
+	*memoryStore
+}
+
+type MemorySyncedLyricsRepository struct {
+	*memoryStore
+}
+
+type MemoryTextRevisionRepository struct {
+	*memoryStore
+}
+
+// NewMemoryRepositories returns repositories sharing one in-memory store.
+func NewMemoryRepositories() Repositories {
+	store := &memoryStore{
+		songs:       make(map[int]*models.Song),
+		groups:      make(map[int]*models.Group),
+		albums:      make(map[int]*models.Album),
+		labels:      map[string]map[string]bool{labelGenres: {}, labelTags: {}},
+		sections:    make(map[int][]models.Section),
+		synced:      make(map[int]*models.SyncedLyrics),
+		revisions:   make(map[int][]models.TextRevision),
+		nextSongID:  1,
+		nextGroupID: 1,
+		nextAlbumID: 1,
+	}
+	return Repositories{
+		Songs:     &MemorySongRepository{store},
+		Groups:    &MemoryGroupRepository{store},
+		Albums:    &MemoryAlbumRepository{store},
+		Genres:    &MemoryLabelRepository{store, labelGenres},
+		Tags:      &MemoryLabelRepository{store, labelTags},
+		Sections:  &MemorySectionRepository{store},
+		Synced:    &MemorySyncedLyricsRepository{store},
+		Revisions: &MemoryTextRevisionRepository{store},
+	}
+}
+
+// copySong returns a copy that shares no pointers or maps with the stored
+// song, with the current names of its group and album.
+func (store *memoryStore) copySong(song *models.Song) *models.Song {
+	copied := *song
+	if group, ok := store.groups[song.GroupID]; ok {
+		copied.Group = group.Name
+	}
+	copied.Album = ""
+	if song.AlbumID != nil {
+		albumID, trackNumber := *song.AlbumID, *song.TrackNumber
+		copied.AlbumID, copied.TrackNumber = &albumID, &trackNumber
+		if album, ok := store.albums[albumID]; ok {
+			copied.Album = album.Title
+		}
+	}
+	if song.ReleaseDate != nil {
+		releaseDate := *song.ReleaseDate
+		copied.ReleaseDate = &releaseDate
+	}
+	if song.EnrichmentRetryAt != nil {
+		retryAt := *song.EnrichmentRetryAt
+		copied.EnrichmentRetryAt = &retryAt
+	}
+	copied.Genres = append([]string{}, song.Genres...)
+	copied.Tags = append([]string{}, song.Tags...)
+	copied.Sources = models.Sources{}
+	for field, source := range song.Sources {
+		copied.Sources[field] = source
+	}
+	return &copied
+}
+
+func (repository *MemorySongRepository) Create(ctx context.Context, song models.Song) (*models.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if _, ok := repository.groups[song.GroupID]; !ok {
+		return nil, ErrGroupNotFound
+	}
+	if repository.songByName(song.GroupID, song.Song) != nil {
+		return nil, ErrSongExists
+	}
+
+	now := time.Now()
+	stored := repository.copySong(&song)
+	stored.ID = repository.nextSongID
+	stored.EnrichmentAttempts = 0
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	repository.nextSongID++
+
+	repository.songs[stored.ID] = stored
+	return repository.copySong(stored), nil
+}
+
+func (repository *MemorySongRepository) Get(ctx context.Context, id int) (*models.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	song, ok := repository.songs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return repository.copySong(song), nil
+}
+
+// songByName finds the song of the group with the name ignoring case and
+// surrounding spaces, like the unique index in Postgres.
+func (store *memoryStore) songByName(groupID int, name string) *models.Song {
+	key := strings.ToLower(strings.TrimSpace(name))
+	for _, song := range store.songs {
+		if song.GroupID == groupID && strings.ToLower(strings.TrimSpace(song.Song)) == key {
+			return song
+		}
+	}
+	return nil
+}
+
+func (repository *MemorySongRepository) FindByName(ctx context.Context, groupID int, name string) (*models.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	song := repository.songByName(groupID, name)
+	if song == nil {
+		return nil, ErrNotFound
+	}
+	return repository.copySong(song), nil
+}
+
+func (repository *MemorySongRepository) List(ctx context.Context, filter SongQuery) ([]models.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	var matched []*models.Song
+	scores := make(map[int]*float64)
+	values := make(map[int][]interface{})
+	for _, song := range repository.songs {
+		if !repository.matches(song, filter) {
+			continue
+		}
+		values[song.ID] = repository.sortValues(song, filter.Order)
+		if keyset := filter.Keyset; keyset != nil {
+			position := compareKeys(values[song.ID], song.ID, keyset.Values, keyset.ID, filter.Order)
+			if (keyset.Before && position >= 0) || (!keyset.Before && position <= 0) {
+				continue
+			}
+		}
+		matched = append(matched, song)
+		scores[song.ID] = repository.nameScore(song, filter)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		first, second := matched[i], matched[j]
+		if len(filter.Order) == 0 {
+			if firstScore, secondScore := scores[first.ID], scores[second.ID]; firstScore != nil && secondScore != nil && *firstScore != *secondScore {
+				return *firstScore > *secondScore
+			}
+		}
+		return compareKeys(values[first.ID], first.ID, values[second.ID], second.ID, filter.Order) < 0
+	})
+
+	if filter.Keyset != nil && filter.Keyset.Before {
+		// the songs right before the keyset song are the last ones
+		if filter.Limit > 0 && filter.Limit < len(matched) {
+			matched = matched[len(matched)-filter.Limit:]
+		}
+	} else {
+		offset := filter.Offset
+		if filter.Keyset != nil {
+			offset = 0
+		}
+		if offset >= len(matched) {
+			return nil, nil
+		}
+		matched = matched[offset:]
+		if filter.Limit > 0 && filter.Limit < len(matched) {
+			matched = matched[:filter.Limit]
+		}
+	}
+
+	songs := make([]models.Song, 0, len(matched))
+	for _, song := range matched {
+		copied := repository.copySong(song)
+		copied.Score = scores[song.ID]
+		songs = append(songs, *copied)
+	}
+	return songs, nil
+}
+
+func (repository *MemorySongRepository) Count(ctx context.Context, filter SongQuery) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	count := 0
+	for _, song := range repository.songs {
+		if repository.matches(song, filter) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// sortValues returns the values the song is sorted by in the order.
+func (store *memoryStore) sortValues(song *models.Song, order []SongOrder) []interface{} {
+	values := make([]interface{}, len(order))
+	for i, field := range order {
+		if field.Field == models.SortGroup {
+			values[i] = store.groups[song.GroupID].Name
+			continue
+		}
+		values[i] = SortValue(*song, field.Field)
+	}
+	return values
+}
+
+// compareKeys orders songs given by their sort values and ids like
+// songOrderBy does in Postgres: by the order fields with missing values
+// last, then by id.
+func compareKeys(first []interface{}, firstID int, second []interface{}, secondID int, order []SongOrder) int {
+	for i, field := range order {
+		if first[i] == nil || second[i] == nil {
+			if nulls := compareNulls(first[i] == nil, second[i] == nil); nulls != 0 {
+				return nulls
+			}
+			continue
+		}
+		result := compareValues(first[i], second[i])
+		if field.Desc {
+			result = -result
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return cmp.Compare(firstID, secondID)
+}
+
+// compareValues compares two sort values of the same field.
+func compareValues(first, second interface{}) int {
+	switch first := first.(type) {
+	case string:
+		return strings.Compare(strings.ToLower(first), strings.ToLower(second.(string)))
+	case int:
+		return cmp.Compare(first, second.(int))
+	case time.Time:
+		return first.Compare(second.(time.Time))
+	default:
+		return 0
+	}
+}
+
+// compareNulls puts a missing value after a present one in either direction.
+func compareNulls(firstMissing, secondMissing bool) int {
+	switch {
+	case firstMissing && !secondMissing:
+		return 1
+	case !firstMissing && secondMissing:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func (store *memoryStore) matches(song *models.Song, filter SongQuery) bool {
+	if filter.GroupID != 0 && song.GroupID != filter.GroupID {
+		return false
+	}
+	if filter.AlbumID != 0 && (song.AlbumID == nil || *song.AlbumID != filter.AlbumID) {
+		return false
+	}
+	if filter.Group != "" && !matchesName(store.groups[song.GroupID].Name, filter.Group, filter.NameMatch) {
+		return false
+	}
+	if filter.Song != "" && !matchesName(song.Song, filter.Song, filter.NameMatch) {
+		return false
+	}
+	if !matchesLabels(song, filter) {
+		return false
+	}
+	if filter.EnrichmentStatus != "" && song.EnrichmentStatus != filter.EnrichmentStatus {
+		return false
+	}
+	if filter.EnrichmentDue != nil && song.EnrichmentRetryAt != nil && song.EnrichmentRetryAt.After(*filter.EnrichmentDue) {
+		return false
+	}
+	if filter.ReleasedFrom != nil || filter.ReleasedTo != nil {
+		if song.ReleaseDate == nil {
+			return false
+		}
+		if filter.ReleasedFrom != nil && !song.ReleaseDate.End().After(*filter.ReleasedFrom) {
+			return false
+		}
+		if filter.ReleasedTo != nil && !song.ReleaseDate.Time.Before(*filter.ReleasedTo) {
+			return false
+		}
+	}
+	if filter.CreatedFrom != nil && song.CreatedAt.Before(*filter.CreatedFrom) {
+		return false
+	}
+	if filter.CreatedTo != nil && !song.CreatedAt.Before(*filter.CreatedTo) {
+		return false
+	}
+	if filter.UpdatedSince != nil && song.UpdatedAt.Before(*filter.UpdatedSince) {
+		return false
+	}
+	if filter.HasLink != nil && (song.Link != "") != *filter.HasLink {
+		return false
+	}
+	if filter.HasText != nil && (song.Text != "") != *filter.HasText {
+		return false
+	}
+	return true
+}
+
+func (repository *MemorySongRepository) Update(ctx context.Context, id int, update SongUpdate) (*models.Song, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	song, ok := repository.songs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if update.IfEnrichmentStatus != "" && song.EnrichmentStatus != update.IfEnrichmentStatus {
+		return nil, ErrNotFound
+	}
+	update = preserveFields(update, song.Sources)
+
+	updated := repository.copySong(song)
+	if update.GroupID != nil {
+		if _, ok := repository.groups[*update.GroupID]; !ok {
+			return nil, ErrGroupNotFound
+		}
+		if song.AlbumID != nil && repository.albums[*song.AlbumID].GroupID != *update.GroupID {
+			return nil, fmt.Errorf("%w: song %d is on album %d", ErrTrackGroup, id, *song.AlbumID)
+		}
+		updated.GroupID = *update.GroupID
+	}
+	if update.Song != nil {
+		updated.Song = *update.Song
+	}
+	if other := repository.songByName(updated.GroupID, updated.Song); other != nil && other.ID != id {
+		return nil, ErrSongExists
+	}
+	if update.SetReleaseDate {
+		updated.ReleaseDate = nil
+		if update.ReleaseDate != nil {
+			releaseDate := *update.ReleaseDate
+			updated.ReleaseDate = &releaseDate
+		}
+	}
+	if update.Text != nil {
+		updated.Text = *update.Text
+		repository.storeSections(id, update.Sections)
+		if update.SyncedLyrics != nil {
+			repository.synced[id] = copySyncedLyrics(update.SyncedLyrics)
+		} else if updated.Text != song.Text {
+			delete(repository.synced, id)
+		}
+		repository.recordRevision(id, updated.Text, update.Revision)
+	}
+	if update.Link != nil {
+		updated.Link = *update.Link
+	}
+	for field, source := range update.Sources {
+		updated.Sources[field] = source
+	}
+	if update.EnrichmentStatus != nil {
+		updated.EnrichmentStatus = *update.EnrichmentStatus
+		updated.EnrichmentRetryAt = nil
+	}
+	if update.EnrichmentError != nil {
+		updated.EnrichmentError = *update.EnrichmentError
+	}
+	if update.CountAttempt {
+		updated.EnrichmentAttempts++
+	}
+	updated.UpdatedAt = time.Now()
+
+	repository.songs[id] = updated
+	return repository.copySong(updated), nil
+}
+
+func (repository *MemorySongRepository) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if _, ok := repository.songs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(repository.songs, id)
+	delete(repository.sections, id)
+	delete(repository.synced, id)
+	delete(repository.revisions, id)
+
+	log.Printf("Deleted song with id %d", id)
+	return nil
+}
+
+func (repository *MemorySongRepository) RecordEnrichmentFailure(ctx context.Context, id int, cause string, maxAttempts int, retryAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	song, ok := repository.songs[id]
+	if !ok || song.EnrichmentStatus != models.EnrichmentPending {
+		return nil
+	}
+
+	song.EnrichmentAttempts++
+	song.EnrichmentError = cause
+	if song.EnrichmentAttempts >= maxAttempts {
+		song.EnrichmentStatus = models.EnrichmentFailed
+		song.EnrichmentRetryAt = nil
+	} else {
+		song.EnrichmentRetryAt = &retryAt
+	}
+	song.UpdatedAt = time.Now()
+	return nil
+}
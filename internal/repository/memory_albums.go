@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"testForWork/internal/models"
+	"time"
+)
+
+// copyAlbum returns a copy with the current group name and track count, the
+// caller must hold the lock.
+func (store *memoryStore) copyAlbum(album *models.Album) *models.Album {
+	copied := *album
+	if album.ReleaseDate != nil {
+		releaseDate := *album.ReleaseDate
+		copied.ReleaseDate = &releaseDate
+	}
+	if group, ok := store.groups[album.GroupID]; ok {
+		copied.Group = group.Name
+	}
+	copied.TrackCount = 0
+	for _, song := range store.songs {
+		if song.AlbumID != nil && *song.AlbumID == album.ID {
+			copied.TrackCount++
+		}
+	}
+	return &copied
+}
+
+// clearTracks takes every song off the album, the caller must hold the
+// write lock.
+func (store *memoryStore) clearTracks(id int, now time.Time) {
+	for _, song := range store.songs {
+		if song.AlbumID != nil && *song.AlbumID == id {
+			song.AlbumID, song.TrackNumber = nil, nil
+			song.UpdatedAt = now
+		}
+	}
+}
+
+func (repository *MemoryAlbumRepository) Create(ctx context.Context, album models.Album) (*models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if _, ok := repository.groups[album.GroupID]; !ok {
+		return nil, ErrGroupNotFound
+	}
+
+	now := time.Now()
+	stored := repository.copyAlbum(&album)
+	stored.ID = repository.nextAlbumID
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	repository.nextAlbumID++
+
+	repository.albums[stored.ID] = stored
+	return repository.copyAlbum(stored), nil
+}
+
+func (repository *MemoryAlbumRepository) Get(ctx context.Context, id int) (*models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	album, ok := repository.albums[id]
+	if !ok {
+		return nil, ErrAlbumNotFound
+	}
+	return repository.copyAlbum(album), nil
+}
+
+func (repository *MemoryAlbumRepository) List(ctx context.Context, groupID, limit, offset int) ([]models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	var ids []int
+	for id, album := range repository.albums {
+		if groupID == 0 || album.GroupID == groupID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	if offset >= len(ids) {
+		return nil, nil
+	}
+	ids = ids[offset:]
+	if limit > 0 && limit < len(ids) {
+		ids = ids[:limit]
+	}
+
+	albums := make([]models.Album, 0, len(ids))
+	for _, id := range ids {
+		albums = append(albums, *repository.copyAlbum(repository.albums[id]))
+	}
+	return albums, nil
+}
+
+func (repository *MemoryAlbumRepository) Update(ctx context.Context, id int, update AlbumUpdate) (*models.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	album, ok := repository.albums[id]
+	if !ok {
+		return nil, ErrAlbumNotFound
+	}
+
+	updated := repository.copyAlbum(album)
+	if update.GroupID != nil {
+		if _, ok := repository.groups[*update.GroupID]; !ok {
+			return nil, ErrGroupNotFound
+		}
+		for _, song := range repository.songs {
+			if song.AlbumID != nil && *song.AlbumID == id && song.GroupID != *update.GroupID {
+				return nil, fmt.Errorf("%w: the album has tracks of its current group", ErrTrackGroup)
+			}
+		}
+		updated.GroupID = *update.GroupID
+	}
+	if update.Title != nil {
+		updated.Title = *update.Title
+	}
+	if update.SetReleaseDate {
+		updated.ReleaseDate = nil
+		if update.ReleaseDate != nil {
+			releaseDate := *update.ReleaseDate
+			updated.ReleaseDate = &releaseDate
+		}
+	}
+	if update.Type != nil {
+		updated.Type = *update.Type
+	}
+	updated.UpdatedAt = time.Now()
+
+	repository.albums[id] = updated
+	return repository.copyAlbum(updated), nil
+}
+
+func (repository *MemoryAlbumRepository) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if _, ok := repository.albums[id]; !ok {
+		return ErrAlbumNotFound
+	}
+	repository.clearTracks(id, time.Now())
+	delete(repository.albums, id)
+
+	log.Printf("Deleted album with id %d", id)
+	return nil
+}
+
+func (repository *MemoryAlbumRepository) SetTracks(ctx context.Context, id int, songIDs []int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	album, ok := repository.albums[id]
+	if !ok {
+		return ErrAlbumNotFound
+	}
+	// Checked up front, nothing may change when a song is missing.
+	for _, songID := range songIDs {
+		song, ok := repository.songs[songID]
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrNotFound, songID)
+		}
+		if song.GroupID != album.GroupID {
+			return fmt.Errorf("%w: song %d", ErrTrackGroup, songID)
+		}
+	}
+
+	now := time.Now()
+	repository.clearTracks(id, now)
+	for i, songID := range songIDs {
+		song := repository.songs[songID]
+		albumID, trackNumber := id, i+1
+		song.AlbumID, song.TrackNumber = &albumID, &trackNumber
+		song.UpdatedAt = now
+	}
+	return nil
+}
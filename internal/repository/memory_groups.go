@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"testForWork/internal/models"
+	"time"
+)
+
+// groupKey is what makes two group names the same group, like the
+// LOWER(TRIM(name)) index in Postgres.
+func groupKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func copyGroup(group *models.Group) *models.Group {
+	copied := *group
+	if group.FormedYear != nil {
+		year := *group.FormedYear
+		copied.FormedYear = &year
+	}
+	return &copied
+}
+
+// groupByName finds a group by name, the caller must hold the lock.
+func (store *memoryStore) groupByName(name string) (*models.Group, bool) {
+	key := groupKey(name)
+	for _, group := range store.groups {
+		if groupKey(group.Name) == key {
+			return group, true
+		}
+	}
+	return nil, false
+}
+
+// createGroup stores a new group, the caller must hold the write lock.
+func (store *memoryStore) createGroup(group models.Group) *models.Group {
+	now := time.Now()
+	stored := copyGroup(&group)
+	stored.ID = store.nextGroupID
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	store.nextGroupID++
+
+	store.groups[stored.ID] = stored
+	return copyGroup(stored)
+}
+
+func (repository *MemoryGroupRepository) Create(ctx context.Context, group models.Group) (*models.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if _, ok := repository.groupByName(group.Name); ok {
+		return nil, ErrGroupExists
+	}
+	return repository.createGroup(group), nil
+}
+
+func (repository *MemoryGroupRepository) Get(ctx context.Context, id int) (*models.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	group, ok := repository.groups[id]
+	if !ok {
+		return nil, ErrGroupNotFound
+	}
+	return copyGroup(group), nil
+}
+
+func (repository *MemoryGroupRepository) List(ctx context.Context, limit, offset int) ([]models.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	ids := make([]int, 0, len(repository.groups))
+	for id := range repository.groups {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	if offset >= len(ids) {
+		return nil, nil
+	}
+	ids = ids[offset:]
+	if limit > 0 && limit < len(ids) {
+		ids = ids[:limit]
+	}
+
+	groups := make([]models.Group, 0, len(ids))
+	for _, id := range ids {
+		groups = append(groups, *copyGroup(repository.groups[id]))
+	}
+	return groups, nil
+}
+
+func (repository *MemoryGroupRepository) Update(ctx context.Context, id int, update GroupUpdate) (*models.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	group, ok := repository.groups[id]
+	if !ok {
+		return nil, ErrGroupNotFound
+	}
+
+	updated := copyGroup(group)
+	if update.Name != nil {
+		if other, ok := repository.groupByName(*update.Name); ok && other.ID != id {
+			return nil, ErrGroupExists
+		}
+		updated.Name = *update.Name
+	}
+	if update.FormedYear != nil {
+		year := *update.FormedYear
+		updated.FormedYear = &year
+	}
+	if update.Country != nil {
+		updated.Country = *update.Country
+	}
+	if update.Description != nil {
+		updated.Description = *update.Description
+	}
+	updated.UpdatedAt = time.Now()
+
+	repository.groups[id] = updated
+	return copyGroup(updated), nil
+}
+
+func (repository *MemoryGroupRepository) Delete(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if _, ok := repository.groups[id]; !ok {
+		return ErrGroupNotFound
+	}
+	for _, song := range repository.songs {
+		if song.GroupID == id {
+			return ErrGroupInUse
+		}
+	}
+	for _, album := range repository.albums {
+		if album.GroupID == id {
+			return ErrGroupInUse
+		}
+	}
+	delete(repository.groups, id)
+
+	log.Printf("Deleted group with id %d", id)
+	return nil
+}
+
+func (repository *MemoryGroupRepository) Resolve(ctx context.Context, name string) (*models.Group, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if group, ok := repository.groupByName(name); ok {
+		return copyGroup(group), nil
+	}
+	return repository.createGroup(models.Group{Name: name}), nil
+}
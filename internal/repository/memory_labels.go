@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"testForWork/internal/models"
+	"time"
+)
+
+const (
+	labelGenres = "genres"
+	labelTags   = "tags"
+)
+
+// labelsOf returns the song field holding labels of the kind.
+func labelsOf(song *models.Song, kind string) *[]string {
+	if kind == labelGenres {
+		return &song.Genres
+	}
+	return &song.Tags
+}
+
+func countLabels(carried, wanted []string) int {
+	count := 0
+	for _, name := range wanted {
+		if slices.Contains(carried, name) {
+			count++
+		}
+	}
+	return count
+}
+
+func matchesLabels(song *models.Song, filter SongQuery) bool {
+	genres := countLabels(song.Genres, filter.Genres)
+	tags := countLabels(song.Tags, filter.Tags)
+	if filter.MatchAny {
+		return len(filter.Genres)+len(filter.Tags) == 0 || genres > 0 || tags > 0
+	}
+	return genres == len(filter.Genres) && tags == len(filter.Tags)
+}
+
+func (repository *MemoryLabelRepository) List(ctx context.Context) ([]models.Label, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for name := range repository.labels[repository.kind] {
+		counts[name] = 0
+	}
+	for _, song := range repository.songs {
+		for _, name := range *labelsOf(song, repository.kind) {
+			counts[name]++
+		}
+	}
+
+	labels := make([]models.Label, 0, len(counts))
+	for name, count := range counts {
+		labels = append(labels, models.Label{Name: name, SongCount: count})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels, nil
+}
+
+func (repository *MemoryLabelRepository) Attach(ctx context.Context, songID int, names []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	song, ok := repository.songs[songID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	labels := labelsOf(song, repository.kind)
+	for _, name := range names {
+		repository.labels[repository.kind][name] = true
+		if !slices.Contains(*labels, name) {
+			*labels = append(*labels, name)
+		}
+	}
+	sort.Strings(*labels)
+	song.UpdatedAt = time.Now()
+	return nil
+}
+
+func (repository *MemoryLabelRepository) Detach(ctx context.Context, songID int, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	song, ok := repository.songs[songID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	labels := labelsOf(song, repository.kind)
+	*labels = slices.DeleteFunc(*labels, func(label string) bool {
+		return label == name
+	})
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"strings"
+	"testForWork/internal/models"
+)
+
+// trigrams returns the trigrams of the words the way pg_trgm builds them:
+// every word is lower-cased and padded with two spaces in front and one
+// behind.
+func trigrams(words []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// wordSimilarity approximates pg_trgm word_similarity: the share of the
+// query trigrams found in the best run of consecutive words of the name.
+func wordSimilarity(query, name string) float64 {
+	queryTrigrams := trigrams(searchWords(query))
+	if len(queryTrigrams) == 0 {
+		return 0
+	}
+	words := searchWords(name)
+	best := 0
+	for start := range words {
+		for end := start + 1; end <= len(words); end++ {
+			common := 0
+			for trigram := range trigrams(words[start:end]) {
+				if queryTrigrams[trigram] {
+					common++
+				}
+			}
+			best = max(best, common)
+		}
+	}
+	return float64(best) / float64(len(queryTrigrams))
+}
+
+// matchesName compares a name with its filter in the match mode.
+func matchesName(name, filter, mode string) bool {
+	name, filter = strings.ToLower(name), strings.ToLower(filter)
+	switch mode {
+	case models.NameMatchPrefix:
+		return strings.HasPrefix(name, filter)
+	case models.NameMatchContains:
+		return strings.Contains(name, filter)
+	case models.NameMatchFuzzy:
+		return wordSimilarity(filter, name) >= fuzzyThreshold
+	default:
+		return name == strings.TrimSpace(filter)
+	}
+}
+
+// nameScore is the mean similarity of the song names to the filters in the
+// fuzzy mode, nil otherwise.
+func (store *memoryStore) nameScore(song *models.Song, filter SongQuery) *float64 {
+	if filter.NameMatch != models.NameMatchFuzzy {
+		return nil
+	}
+	var total float64
+	var count int
+	if filter.Group != "" {
+		total += wordSimilarity(filter.Group, store.groups[song.GroupID].Name)
+		count++
+	}
+	if filter.Song != "" {
+		total += wordSimilarity(filter.Song, song.Song)
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	score := total / float64(count)
+	return &score
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"testForWork/internal/models"
+	"time"
+)
+
+// recordRevision adds a revision with the text unless it equals the latest
+// one, the caller holds the lock.
+func (store *memoryStore) recordRevision(songID int, text string, note models.RevisionNote) {
+	revisions := store.revisions[songID]
+	latest := ""
+	if len(revisions) > 0 {
+		latest = *revisions[len(revisions)-1].Text
+	}
+	if text == latest {
+		return
+	}
+	store.revisions[songID] = append(revisions, models.TextRevision{
+		Revision:  len(revisions) + 1,
+		Text:      &text,
+		Author:    note.Author,
+		Comment:   note.Comment,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (repository *MemoryTextRevisionRepository) List(ctx context.Context, songID, limit, offset int) ([]models.TextRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	stored := repository.revisions[songID]
+	revisions := []models.TextRevision{}
+	for i := len(stored) - 1 - offset; i >= 0; i-- {
+		if limit > 0 && len(revisions) == limit {
+			break
+		}
+		revision := stored[i]
+		revision.Text = nil
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (repository *MemoryTextRevisionRepository) Get(ctx context.Context, songID, number int) (*models.TextRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	revisions := repository.revisions[songID]
+	if number < 1 || number > len(revisions) {
+		return nil, ErrRevisionNotFound
+	}
+	revision := revisions[number-1]
+	text := *revision.Text
+	revision.Text = &text
+	return &revision, nil
+}
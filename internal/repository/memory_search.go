@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testForWork/internal/models"
+	"unicode"
+)
+
+// Weights of matches in the song name, the group name and the lyrics, the
+// same ts_rank_cd gives to the A, B and C parts of the Postgres vector.
+const (
+	songNameWeight  = 1.0
+	groupNameWeight = 0.4
+	lyricsWeight    = 0.2
+)
+
+// searchTerms splits a query into the words to find and the words, prefixed
+// with a minus, that must not be there. Stemming is approximated by matching
+// words that start with a term.
+func searchTerms(query string) (include, exclude []string) {
+	for _, field := range strings.Fields(query) {
+		words := searchWords(field)
+		if strings.HasPrefix(field, "-") {
+			exclude = append(exclude, words...)
+		} else if !strings.EqualFold(field, "or") {
+			include = append(include, words...)
+		}
+	}
+	return include, exclude
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func matchesTerm(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// countTerms returns how many words of the text match a term and which terms
+// were found.
+func countTerms(text string, terms []string) (int, map[string]bool) {
+	count, found := 0, make(map[string]bool)
+	for _, word := range searchWords(text) {
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				count++
+				found[term] = true
+			}
+		}
+	}
+	return count, found
+}
+
+// highlight wraps the words of the text matching a term in <b></b>.
+func highlight(text string, terms []string) string {
+	var builder strings.Builder
+	word := []rune{}
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		if matchesTerm(strings.ToLower(string(word)), terms) {
+			builder.WriteString("<b>" + string(word) + "</b>")
+		} else {
+			builder.WriteString(string(word))
+		}
+		word = word[:0]
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		builder.WriteRune(r)
+	}
+	flush()
+	return builder.String()
+}
+
+// searchSnippet returns the verse with the most matches, or the beginning of
+// the lyrics when no verse matches.
+func searchSnippet(text string, terms []string) string {
+	best, bestCount := "", 0
+	for _, verse := range strings.Split(text, "\n\n") {
+		if count, _ := countTerms(verse, terms); count > bestCount {
+			best, bestCount = verse, count
+		}
+	}
+	if bestCount == 0 {
+		words := strings.Fields(text)
+		if len(words) > 35 {
+			words = words[:35]
+		}
+		return strings.Join(words, " ")
+	}
+	return highlight(best, terms)
+}
+
+func (repository *MemorySongRepository) Search(ctx context.Context, search SongSearch) ([]models.SongSearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	include, exclude := searchTerms(search.Text)
+	if len(include) == 0 {
+		return []models.SongSearchResult{}, nil
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	results := []models.SongSearchResult{}
+	for _, song := range repository.songs {
+		group := repository.groups[song.GroupID].Name
+		found := make(map[string]bool)
+		rank := 0.0
+		for _, part := range []struct {
+			text   string
+			weight float64
+		}{{song.Song, songNameWeight}, {group, groupNameWeight}, {song.Text, lyricsWeight}} {
+			count, terms := countTerms(part.text, include)
+			rank += float64(count) * part.weight
+			for term := range terms {
+				found[term] = true
+			}
+			if excluded, _ := countTerms(part.text, exclude); excluded > 0 {
+				found = nil
+				break
+			}
+		}
+		if len(found) < len(uniqueTerms(include)) {
+			continue
+		}
+		results = append(results, models.SongSearchResult{
+			Song:    *repository.copySong(song),
+			Rank:    rank,
+			Snippet: searchSnippet(song.Text, include),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if search.Offset >= len(results) {
+		return []models.SongSearchResult{}, nil
+	}
+	results = results[search.Offset:]
+	if search.Limit > 0 && search.Limit < len(results) {
+		results = results[:search.Limit]
+	}
+	return results, nil
+}
+
+func uniqueTerms(terms []string) map[string]bool {
+	unique := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		unique[term] = true
+	}
+	return unique
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"testForWork/internal/models"
+)
+
+// copySection returns a copy that shares no slices or pointers with section.
+func copySection(section models.Section) models.Section {
+	section.Lines = slices.Clone(section.Lines)
+	if section.RepeatOf != nil {
+		repeatOf := *section.RepeatOf
+		section.RepeatOf = &repeatOf
+	}
+	return section
+}
+
+// storeSections replaces the sections of the song, the caller holds the lock.
+func (store *memoryStore) storeSections(songID int, sections []models.Section) {
+	if len(sections) == 0 {
+		delete(store.sections, songID)
+		return
+	}
+	stored := make([]models.Section, len(sections))
+	for i, section := range sections {
+		stored[i] = copySection(section)
+		if section.RepeatOf != nil {
+			stored[i].Lines = nil
+		} else if stored[i].Lines == nil {
+			stored[i].Lines = []string{}
+		}
+	}
+	store.sections[songID] = stored
+}
+
+func (repository *MemorySectionRepository) List(ctx context.Context, songID int) ([]models.Section, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	sections := []models.Section{}
+	for _, section := range repository.sections[songID] {
+		sections = append(sections, copySection(section))
+	}
+	return sections, nil
+}
+
+func (repository *MemorySectionRepository) Replace(ctx context.Context, songID int, text string, sections []models.Section) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	song, ok := repository.songs[songID]
+	if !ok {
+		return false, ErrNotFound
+	}
+	if song.Text != text {
+		return false, nil
+	}
+	repository.storeSections(songID, sections)
+	return true, nil
+}
+
+func (repository *MemorySectionRepository) Unparsed(ctx context.Context) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	ids := []int{}
+	for id, song := range repository.songs {
+		if strings.TrimSpace(song.Text) != "" && len(repository.sections[id]) == 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
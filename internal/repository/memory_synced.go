@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testForWork/internal/models"
+)
+
+// copySyncedLyrics returns a copy that shares no maps or slices with lyrics.
+func copySyncedLyrics(lyrics *models.SyncedLyrics) *models.SyncedLyrics {
+	copied := &models.SyncedLyrics{
+		Tags:  maps.Clone(lyrics.Tags),
+		Lines: slices.Clone(lyrics.Lines),
+	}
+	for i, line := range copied.Lines {
+		copied.Lines[i].Words = slices.Clone(line.Words)
+	}
+	return copied
+}
+
+func (repository *MemorySyncedLyricsRepository) Get(ctx context.Context, songID int) (*models.SyncedLyrics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	lyrics, ok := repository.synced[songID]
+	if !ok {
+		return nil, ErrSyncedLyricsNotFound
+	}
+	return copySyncedLyrics(lyrics), nil
+}
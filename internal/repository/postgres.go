@@ -0,0 +1,630 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"testForWork/internal/models"
+	"time"
+)
+
+// releaseDateEnd is the first day after the release period of a song.
+const releaseDateEnd = `(s.release_date + CASE s.release_date_precision
+		WHEN 'year' THEN INTERVAL '1 year'
+		WHEN 'month' THEN INTERVAL '1 month'
+		ELSE INTERVAL '1 day' END)`
+
+var songColumns = `s.id, s.group_id, g.name, s.song_name, s.album_id, a.title, s.track_number,
+	` + genreTables.namesOf("s.id") + `, ` + tagTables.namesOf("s.id") + `,
+	s.release_date, s.release_date_precision, s.text, s.link,
+	s.enrichment_status, s.enrichment_attempts, s.enrichment_error, s.enrichment_retry_at, s.details_sources, s.created_at, s.updated_at`
+
+// selectSongs reads songs with their group and album names from a table or
+// a CTE aliased as s.
+func selectSongs(source string) string {
+	return `SELECT ` + songColumns + ` FROM ` + source + ` s
+		JOIN groups g ON g.id = s.group_id
+		LEFT JOIN albums a ON a.id = s.album_id`
+}
+
+// fuzzyThreshold is the least word similarity of a fuzzy name match.
+const fuzzyThreshold = 0.3
+
+// songQuerier runs song queries on the database or within a transaction.
+type songQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// querier returns where to run a song query in the match mode and a function
+// releasing it. The fuzzy mode filters with the <% operator, which the
+// trigram indexes support but which takes its threshold from a setting, so
+// its queries run in a read-only transaction that sets the threshold.
+func (repository *PostgresSongRepository) querier(ctx context.Context, mode string) (songQuerier, func(), error) {
+	if mode != models.NameMatchFuzzy {
+		return repository.db, func() {}, nil
+	}
+
+	tx, err := repository.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	threshold := strconv.FormatFloat(fuzzyThreshold, 'f', -1, 64)
+	_, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, threshold)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return tx, func() { tx.Rollback() }, nil
+}
+
+// nameCondition compares the column with a name filter parameter prepared by
+// nameArg for the match mode.
+func nameCondition(column, param, mode string) string {
+	switch mode {
+	case models.NameMatchPrefix, models.NameMatchContains:
+		return fmt.Sprintf(`%s ILIKE %s`, column, param)
+	case models.NameMatchFuzzy:
+		return fmt.Sprintf(`%s <%% %s`, param, column)
+	default:
+		return fmt.Sprintf(`LOWER(%s) = LOWER(TRIM(%s))`, column, param)
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// nameArg turns a name filter into a LIKE pattern in the prefix and contains
+// modes. An empty filter stays empty.
+func nameArg(value, mode string) string {
+	if value == "" {
+		return ""
+	}
+	switch mode {
+	case models.NameMatchPrefix:
+		return likeEscaper.Replace(value) + "%"
+	case models.NameMatchContains:
+		return "%" + likeEscaper.Replace(value) + "%"
+	default:
+		return value
+	}
+}
+
+// sortColumns maps sort fields to the expressions songs are sorted by.
+var sortColumns = map[string]string{
+	models.SortID:          "s.id",
+	models.SortSong:        "LOWER(s.song_name)",
+	models.SortGroup:       "LOWER(g.name)",
+	models.SortReleaseDate: "s.release_date_sort",
+	models.SortTrackNumber: "s.track_number",
+	models.SortCreatedAt:   "s.created_at",
+	models.SortUpdatedAt:   "s.updated_at",
+}
+
+// sortParams casts keyset parameters to compare with sortColumns.
+var sortParams = map[string]string{
+	models.SortID:          "%s::int",
+	models.SortSong:        "LOWER(%s::text)",
+	models.SortGroup:       "LOWER(%s::text)",
+	models.SortReleaseDate: "%s::timestamp",
+	models.SortTrackNumber: "%s::int",
+	models.SortCreatedAt:   "%s::timestamptz",
+	models.SortUpdatedAt:   "%s::timestamptz",
+}
+
+// songOrderBy builds the ORDER BY list of the query, ending with the id so
+// pages are stable. Listing the songs before a keyset runs it backwards.
+func songOrderBy(filter SongQuery) string {
+	reverse := filter.Keyset != nil && filter.Keyset.Before
+	direction := func(desc bool) string {
+		if desc != reverse {
+			return " DESC"
+		}
+		return " ASC"
+	}
+	nulls := " NULLS LAST"
+	if reverse {
+		nulls = " NULLS FIRST"
+	}
+
+	var order []string
+	if len(filter.Order) == 0 && filter.NameMatch == models.NameMatchFuzzy {
+		order = append(order, "score DESC NULLS LAST")
+	}
+	for _, field := range filter.Order {
+		if column, ok := sortColumns[field.Field]; ok {
+			order = append(order, column+direction(field.Desc)+nulls)
+		}
+	}
+	return strings.Join(append(order, "s.id"+direction(false)), ", ")
+}
+
+// songNameIndex keeps song names unique within a group.
+const songNameIndex = "idx_songs_group_song_name"
+
+func isDuplicateSong(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == pqUniqueViolation && pqErr.Constraint == songNameIndex
+}
+
+type PostgresSongRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepositories returns all repositories backed by the database.
+func NewPostgresRepositories(db *sql.DB) Repositories {
+	return Repositories{
+		Songs:     NewPostgresSongRepository(db),
+		Groups:    NewPostgresGroupRepository(db),
+		Albums:    NewPostgresAlbumRepository(db),
+		Genres:    NewPostgresGenreRepository(db),
+		Tags:      NewPostgresTagRepository(db),
+		Sections:  NewPostgresSectionRepository(db),
+		Synced:    NewPostgresSyncedLyricsRepository(db),
+		Revisions: NewPostgresTextRevisionRepository(db),
+	}
+}
+
+func NewPostgresSongRepository(db *sql.DB) *PostgresSongRepository {
+	return &PostgresSongRepository{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSong reads the song columns followed by the extra columns, if any.
+func scanSong(row rowScanner, extra ...interface{}) (*models.Song, error) {
+	var song models.Song
+	var releaseDate *time.Time
+	var precision string
+	var album *string
+	dest := []interface{}{
+		&song.ID,
+		&song.GroupID,
+		&song.Group,
+		&song.Song,
+		&song.AlbumID,
+		&album,
+		&song.TrackNumber,
+		pq.Array(&song.Genres),
+		pq.Array(&song.Tags),
+		&releaseDate,
+		&precision,
+		&song.Text,
+		&song.Link,
+		&song.EnrichmentStatus,
+		&song.EnrichmentAttempts,
+		&song.EnrichmentError,
+		&song.EnrichmentRetryAt,
+		&song.Sources,
+		&song.CreatedAt,
+		&song.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if releaseDate != nil {
+		song.ReleaseDate = &models.ReleaseDate{Time: *releaseDate, Precision: precision}
+	}
+	if album != nil {
+		song.Album = *album
+	}
+	if song.Genres == nil {
+		song.Genres = []string{}
+	}
+	if song.Tags == nil {
+		song.Tags = []string{}
+	}
+	return &song, nil
+}
+
+// textArray passes a nil slice as an empty array rather than NULL.
+func textArray(values []string) interface{} {
+	if values == nil {
+		values = []string{}
+	}
+	return pq.Array(values)
+}
+
+// releaseDateArgs splits a release date into the release_date and
+// release_date_precision column values.
+func releaseDateArgs(date *models.ReleaseDate) (*time.Time, string) {
+	if date == nil {
+		return nil, models.PrecisionDay
+	}
+	return &date.Time, date.Precision
+}
+
+func (repository *PostgresSongRepository) Create(ctx context.Context, song models.Song) (*models.Song, error) {
+	sources := song.Sources
+	if sources == nil {
+		sources = models.Sources{}
+	}
+	date, precision := releaseDateArgs(song.ReleaseDate)
+
+	query := `WITH inserted AS (
+				INSERT INTO songs
+    				("group_id", "song_name", "release_date", "release_date_precision", "text", "link",
+    				 "enrichment_status", "details_sources")
+				VALUES
+				    ($1, $2, $3, $4, $5, $6, $7, $8)
+				RETURNING *
+			  ) ` + selectSongs("inserted")
+
+	newSong, err := scanSong(repository.db.QueryRowContext(
+		ctx, query,
+		song.GroupID, song.Song, date, precision, song.Text, song.Link, song.EnrichmentStatus, sources,
+	))
+	if isDuplicateSong(err) {
+		return nil, ErrSongExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return newSong, nil
+}
+
+func (repository *PostgresSongRepository) Get(ctx context.Context, id int) (*models.Song, error) {
+	query := selectSongs("songs") + ` WHERE s.id = $1`
+
+	song, err := scanSong(repository.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return song, nil
+}
+
+func (repository *PostgresSongRepository) FindByName(ctx context.Context, groupID int, name string) (*models.Song, error) {
+	query := selectSongs("songs") + ` WHERE s.group_id = $1 AND LOWER(TRIM(s.song_name)) = LOWER(TRIM($2))`
+
+	song, err := scanSong(repository.db.QueryRowContext(ctx, query, groupID, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return song, nil
+}
+
+func (repository *PostgresSongRepository) List(ctx context.Context, filter SongQuery) ([]models.Song, error) {
+	var limit *int
+	if filter.Limit > 0 {
+		limit = &filter.Limit
+	}
+
+	offset := filter.Offset
+	if filter.Keyset != nil {
+		offset = 0
+	}
+
+	var builder queryBuilder
+	score := songConditions(&builder, filter)
+	query := `SELECT ` + songColumns + `, ` + score + ` AS score
+		FROM songs s
+		JOIN groups g ON g.id = s.group_id
+		LEFT JOIN albums a ON a.id = s.album_id
+		` + builder.clause() + `
+		ORDER BY ` + songOrderBy(filter) + `
+		LIMIT ` + builder.param(limit) + ` OFFSET ` + builder.param(offset)
+
+	querier, release, err := repository.querier(ctx, filter.NameMatch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rows, err := querier.QueryContext(ctx, query, builder.args...)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var songs []models.Song
+	for rows.Next() {
+		var score *float64
+		song, err := scanSong(rows, &score)
+		if err != nil {
+			return nil, fmt.Errorf("row scan failed: %w", err)
+		}
+		song.Score = score
+		songs = append(songs, *song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration failed: %w", err)
+	}
+	if filter.Keyset != nil && filter.Keyset.Before {
+		slices.Reverse(songs)
+	}
+	return songs, nil
+}
+
+func (repository *PostgresSongRepository) Count(ctx context.Context, filter SongQuery) (int, error) {
+	filter.Keyset = nil
+
+	var builder queryBuilder
+	songConditions(&builder, filter)
+	query := `SELECT COUNT(*) FROM songs s JOIN groups g ON g.id = s.group_id ` + builder.clause()
+
+	querier, release, err := repository.querier(ctx, filter.NameMatch)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var count int
+	if err := querier.QueryRowContext(ctx, query, builder.args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("database query failed: %w", err)
+	}
+	return count, nil
+}
+
+// ts_headline options for a matching verse, shown whole, and for the
+// fallback fragment of the lyrics.
+const (
+	verseHeadline  = `'StartSel=<b>, StopSel=</b>, HighlightAll=true'`
+	lyricsHeadline = `'StartSel=<b>, StopSel=</b>'`
+)
+
+func (repository *PostgresSongRepository) Search(ctx context.Context, search SongSearch) ([]models.SongSearchResult, error) {
+	var limit *int
+	if search.Limit > 0 {
+		limit = &search.Limit
+	}
+
+	// The snippet is the best ranked verse that matches the query on its
+	// own, or the beginning of the lyrics when the match spans verses or is
+	// in the song or group name.
+	query := `WITH q AS (
+			SELECT websearch_to_tsquery('english', $1) AS english,
+			       websearch_to_tsquery('russian', $1) AS russian
+		)
+		SELECT ` + songColumns + `,
+		       ts_rank_cd(s.search_vector, q.english || q.russian) AS rank,
+		       COALESCE(verse.snippet, ts_headline('english', s.text, q.english, ` + lyricsHeadline + `))
+		FROM songs s
+		JOIN groups g ON g.id = s.group_id
+		LEFT JOIN albums a ON a.id = s.album_id
+		CROSS JOIN q
+		LEFT JOIN LATERAL (
+			SELECT CASE WHEN to_tsvector('russian', v.text) @@ q.russian
+			            THEN ts_headline('russian', v.text, q.russian, ` + verseHeadline + `)
+			            ELSE ts_headline('english', v.text, q.english, ` + verseHeadline + `)
+			       END AS snippet
+			FROM regexp_split_to_table(s.text, E'\n\n') AS v(text)
+			WHERE to_tsvector('english', v.text) @@ q.english OR to_tsvector('russian', v.text) @@ q.russian
+			ORDER BY ts_rank(to_tsvector('english', v.text) || to_tsvector('russian', v.text), q.english || q.russian) DESC
+			LIMIT 1
+		) verse ON TRUE
+		WHERE s.search_vector @@ (q.english || q.russian)
+		ORDER BY rank DESC, s.id
+		LIMIT $2 OFFSET $3`
+
+	rows, err := repository.db.QueryContext(ctx, query, search.Text, limit, search.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.SongSearchResult{}
+	for rows.Next() {
+		var result models.SongSearchResult
+		song, err := scanSong(rows, &result.Rank, &result.Snippet)
+		if err != nil {
+			return nil, fmt.Errorf("row scan failed: %w", err)
+		}
+		result.Song = *song
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration failed: %w", err)
+	}
+	return results, nil
+}
+
+func (repository *PostgresSongRepository) Update(ctx context.Context, id int, update SongUpdate) (*models.Song, error) {
+	tx, err := repository.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if update.GroupID != nil {
+		if err := checkTrackGroup(ctx, tx, id, *update.GroupID); err != nil {
+			return nil, err
+		}
+	}
+
+	// The text before the update tells whether the synced lyrics still
+	// match it and the sources which fields to preserve; the lock keeps
+	// them from changing until the update.
+	var previousText string
+	if update.Text != nil || update.PreserveSource != "" {
+		var sources models.Sources
+		err := tx.QueryRowContext(ctx, `SELECT text, details_sources FROM songs WHERE id = $1 FOR UPDATE`, id).Scan(&previousText, &sources)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("database query failed: %w", err)
+		}
+		update = preserveFields(update, sources)
+	}
+
+	var updates []string
+	var params []interface{}
+	counter := 1
+
+	set := func(column string, value interface{}) {
+		updates = append(updates, fmt.Sprintf("%s = $%d", column, counter))
+		params = append(params, value)
+		counter++
+	}
+
+	if update.GroupID != nil {
+		set("group_id", *update.GroupID)
+	}
+	if update.Song != nil {
+		set("song_name", *update.Song)
+	}
+	if update.SetReleaseDate {
+		date, precision := releaseDateArgs(update.ReleaseDate)
+		set("release_date", date)
+		set("release_date_precision", precision)
+	}
+	if update.Text != nil {
+		set("text", *update.Text)
+	}
+	if update.Link != nil {
+		set("link", *update.Link)
+	}
+	if len(update.Sources) > 0 {
+		updates = append(updates, fmt.Sprintf("details_sources = details_sources || $%d::jsonb", counter))
+		params = append(params, update.Sources)
+		counter++
+	}
+	if update.EnrichmentStatus != nil {
+		set("enrichment_status", *update.EnrichmentStatus)
+		updates = append(updates, "enrichment_retry_at = NULL")
+	}
+	if update.EnrichmentError != nil {
+		set("enrichment_error", *update.EnrichmentError)
+	}
+	if update.CountAttempt {
+		updates = append(updates, "enrichment_attempts = enrichment_attempts + 1")
+	}
+
+	if len(updates) == 0 {
+		return repository.Get(ctx, id)
+	}
+	updates = append(updates, "updated_at = NOW()")
+
+	query := fmt.Sprintf(
+		"WITH updated AS (UPDATE songs SET %s WHERE id = $%d AND ($%d = '' OR enrichment_status = $%d) RETURNING *) ",
+		strings.Join(updates, ", "),
+		counter, counter+1, counter+1,
+	) + selectSongs("updated")
+	params = append(params, id, update.IfEnrichmentStatus)
+
+	updatedSong, err := scanSong(tx.QueryRowContext(ctx, query, params...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if isDuplicateSong(err) {
+		return nil, ErrSongExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database update failed: %w", err)
+	}
+
+	if update.Text != nil {
+		if err := replaceSections(ctx, tx, id, update.Sections); err != nil {
+			return nil, err
+		}
+		if update.SyncedLyrics != nil || *update.Text != previousText {
+			if err := replaceSyncedLyrics(ctx, tx, id, update.SyncedLyrics); err != nil {
+				return nil, err
+			}
+		}
+		if err := recordRevision(ctx, tx, id, *update.Text, update.Revision); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return updatedSong, nil
+}
+
+// checkTrackGroup returns ErrTrackGroup when the song is on an album of
+// another group than groupID. It locks the album before the song, in the
+// order SetTracks takes, so neither changes until the transaction ends.
+func checkTrackGroup(ctx context.Context, tx *sql.Tx, songID, groupID int) error {
+	for {
+		var albumID *int
+		err := tx.QueryRowContext(ctx, `SELECT album_id FROM songs WHERE id = $1`, songID).Scan(&albumID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("database query failed: %w", err)
+		}
+
+		var albumGroupID *int
+		if albumID != nil {
+			err := tx.QueryRowContext(ctx, `SELECT group_id FROM albums WHERE id = $1 FOR SHARE`, *albumID).Scan(&albumGroupID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("database query failed: %w", err)
+			}
+		}
+
+		var lockedAlbumID *int
+		err = tx.QueryRowContext(ctx, `SELECT album_id FROM songs WHERE id = $1 FOR UPDATE`, songID).Scan(&lockedAlbumID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("database query failed: %w", err)
+		}
+		if !equalIDs(albumID, lockedAlbumID) {
+			// The song moved to another album meanwhile.
+			continue
+		}
+		if albumGroupID != nil && *albumGroupID != groupID {
+			return fmt.Errorf("%w: song %d is on album %d", ErrTrackGroup, songID, *albumID)
+		}
+		return nil
+	}
+}
+
+func equalIDs(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (repository *PostgresSongRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM songs WHERE id = $1`
+	response, err := repository.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("Database delete failed: %w", err)
+	}
+
+	rowsAffected, _ := response.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	log.Printf("Deleted song with id %d", id)
+	return nil
+}
+
+func (repository *PostgresSongRepository) RecordEnrichmentFailure(ctx context.Context, id int, cause string, maxAttempts int, retryAt time.Time) error {
+	query := `UPDATE songs
+			  SET enrichment_attempts = enrichment_attempts + 1,
+			      enrichment_error = $2,
+			      enrichment_status = CASE WHEN enrichment_attempts + 1 >= $3 THEN $4 ELSE enrichment_status END,
+			      enrichment_retry_at = CASE WHEN enrichment_attempts + 1 >= $3 THEN NULL ELSE $6::timestamptz END,
+			      updated_at = NOW()
+			  WHERE id = $1 AND enrichment_status = $5`
+
+	_, err := repository.db.ExecContext(
+		ctx, query,
+		id, cause, maxAttempts, models.EnrichmentFailed, models.EnrichmentPending, retryAt,
+	)
+	if err != nil {
+		return fmt.Errorf("database update failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testForWork/internal/models"
+	"time"
+)
+
+const albumColumns = `a.id, a.group_id, g.name, a.title, a.release_date, a.release_date_precision, a.album_type,
+	(SELECT COUNT(*) FROM songs WHERE songs.album_id = a.id), a.created_at, a.updated_at`
+
+// selectAlbums reads albums with their group names and track counts from a
+// table or a CTE aliased as a.
+func selectAlbums(source string) string {
+	return `SELECT ` + albumColumns + ` FROM ` + source + ` a JOIN groups g ON g.id = a.group_id`
+}
+
+type PostgresAlbumRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresAlbumRepository(db *sql.DB) *PostgresAlbumRepository {
+	return &PostgresAlbumRepository{db: db}
+}
+
+func scanAlbum(row rowScanner) (*models.Album, error) {
+	var album models.Album
+	var releaseDate *time.Time
+	var precision string
+	err := row.Scan(
+		&album.ID,
+		&album.GroupID,
+		&album.Group,
+		&album.Title,
+		&releaseDate,
+		&precision,
+		&album.Type,
+		&album.TrackCount,
+		&album.CreatedAt,
+		&album.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if releaseDate != nil {
+		album.ReleaseDate = &models.ReleaseDate{Time: *releaseDate, Precision: precision}
+	}
+	return &album, nil
+}
+
+func (repository *PostgresAlbumRepository) Create(ctx context.Context, album models.Album) (*models.Album, error) {
+	date, precision := releaseDateArgs(album.ReleaseDate)
+
+	query := `WITH inserted AS (
+				INSERT INTO albums (group_id, title, release_date, release_date_precision, album_type)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING *
+			  ) ` + selectAlbums("inserted")
+
+	newAlbum, err := scanAlbum(repository.db.QueryRowContext(
+		ctx, query, album.GroupID, album.Title, date, precision, album.Type,
+	))
+	if isPQError(err, pqForeignKeyViolation) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return newAlbum, nil
+}
+
+func (repository *PostgresAlbumRepository) Get(ctx context.Context, id int) (*models.Album, error) {
+	query := selectAlbums("albums") + ` WHERE a.id = $1`
+
+	album, err := scanAlbum(repository.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAlbumNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return album, nil
+}
+
+func (repository *PostgresAlbumRepository) List(ctx context.Context, groupID, limit, offset int) ([]models.Album, error) {
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	query := selectAlbums("albums") + `
+		WHERE ($1 = 0 OR a.group_id = $1)
+		ORDER BY a.id
+		LIMIT $2 OFFSET $3`
+
+	rows, err := repository.db.QueryContext(ctx, query, groupID, limitArg, offset)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var albums []models.Album
+	for rows.Next() {
+		album, err := scanAlbum(rows)
+		if err != nil {
+			return nil, fmt.Errorf("row scan failed: %w", err)
+		}
+		albums = append(albums, *album)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration failed: %w", err)
+	}
+	return albums, nil
+}
+
+func (repository *PostgresAlbumRepository) Update(ctx context.Context, id int, update AlbumUpdate) (*models.Album, error) {
+	var updates []string
+	var params []interface{}
+	counter := 1
+
+	set := func(column string, value interface{}) {
+		updates = append(updates, fmt.Sprintf("%s = $%d", column, counter))
+		params = append(params, value)
+		counter++
+	}
+
+	if update.GroupID != nil {
+		set("group_id", *update.GroupID)
+	}
+	if update.Title != nil {
+		set("title", *update.Title)
+	}
+	if update.SetReleaseDate {
+		date, precision := releaseDateArgs(update.ReleaseDate)
+		set("release_date", date)
+		set("release_date_precision", precision)
+	}
+	if update.Type != nil {
+		set("album_type", *update.Type)
+	}
+
+	if len(updates) == 0 {
+		return repository.Get(ctx, id)
+	}
+	updates = append(updates, "updated_at = NOW()")
+
+	query := fmt.Sprintf(
+		"WITH updated AS (UPDATE albums SET %s WHERE id = $%d RETURNING *) ",
+		strings.Join(updates, ", "),
+		counter,
+	) + selectAlbums("updated")
+	params = append(params, id)
+
+	tx, err := repository.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if update.GroupID != nil {
+		// Locks the album so no track is added meanwhile, like SetTracks.
+		var locked int
+		err := tx.QueryRowContext(ctx, `SELECT id FROM albums WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAlbumNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("database query failed: %w", err)
+		}
+
+		var foreign bool
+		err = tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM songs WHERE album_id = $1 AND group_id <> $2)`,
+			id, *update.GroupID,
+		).Scan(&foreign)
+		if err != nil {
+			return nil, fmt.Errorf("database query failed: %w", err)
+		}
+		if foreign {
+			return nil, fmt.Errorf("%w: the album has tracks of its current group", ErrTrackGroup)
+		}
+	}
+
+	album, err := scanAlbum(tx.QueryRowContext(ctx, query, params...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAlbumNotFound
+	}
+	if isPQError(err, pqForeignKeyViolation) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database update failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return album, nil
+}
+
+func (repository *PostgresAlbumRepository) Delete(ctx context.Context, id int) error {
+	tx, err := repository.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE songs SET album_id = NULL, track_number = NULL, updated_at = NOW() WHERE album_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("database update failed: %w", err)
+	}
+
+	response, err := tx.ExecContext(ctx, `DELETE FROM albums WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("Database delete failed: %w", err)
+	}
+	rowsAffected, _ := response.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrAlbumNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Deleted album with id %d", id)
+	return nil
+}
+
+func (repository *PostgresAlbumRepository) SetTracks(ctx context.Context, id int, songIDs []int) error {
+	tx, err := repository.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locks the album so concurrent tracklist changes do not interleave.
+	var groupID int
+	err = tx.QueryRowContext(ctx, `SELECT group_id FROM albums WHERE id = $1 FOR UPDATE`, id).Scan(&groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAlbumNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE songs SET album_id = NULL, track_number = NULL, updated_at = NOW() WHERE album_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("database update failed: %w", err)
+	}
+
+	// A song of another group is moved too, the rollback undoes it.
+	for i, songID := range songIDs {
+		var songGroupID int
+		err := tx.QueryRowContext(
+			ctx, `UPDATE songs SET album_id = $1, track_number = $2, updated_at = NOW() WHERE id = $3 RETURNING group_id`,
+			id, i+1, songID,
+		).Scan(&songGroupID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrNotFound, songID)
+		}
+		if err != nil {
+			return fmt.Errorf("database update failed: %w", err)
+		}
+		if songGroupID != groupID {
+			return fmt.Errorf("%w: song %d", ErrTrackGroup, songID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
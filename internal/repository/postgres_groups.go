@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"log"
+	"strings"
+	"testForWork/internal/models"
+)
+
+const groupColumns = `id, name, formed_year, country, description, created_at, updated_at`
+
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+type PostgresGroupRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresGroupRepository(db *sql.DB) *PostgresGroupRepository {
+	return &PostgresGroupRepository{db: db}
+}
+
+func scanGroup(row rowScanner) (*models.Group, error) {
+	var group models.Group
+	var formedYear sql.NullInt64
+	err := row.Scan(
+		&group.ID,
+		&group.Name,
+		&formedYear,
+		&group.Country,
+		&group.Description,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if formedYear.Valid {
+		year := int(formedYear.Int64)
+		group.FormedYear = &year
+	}
+	return &group, nil
+}
+
+func isPQError(err error, code string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code
+}
+
+func (repository *PostgresGroupRepository) Create(ctx context.Context, group models.Group) (*models.Group, error) {
+	query := `INSERT INTO groups (name, formed_year, country, description)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING ` + groupColumns
+
+	newGroup, err := scanGroup(repository.db.QueryRowContext(
+		ctx, query, group.Name, group.FormedYear, group.Country, group.Description,
+	))
+	if isPQError(err, pqUniqueViolation) {
+		return nil, ErrGroupExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return newGroup, nil
+}
+
+func (repository *PostgresGroupRepository) Get(ctx context.Context, id int) (*models.Group, error) {
+	query := `SELECT ` + groupColumns + ` FROM groups WHERE id = $1`
+
+	group, err := scanGroup(repository.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return group, nil
+}
+
+func (repository *PostgresGroupRepository) List(ctx context.Context, limit, offset int) ([]models.Group, error) {
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	query := `SELECT ` + groupColumns + ` FROM groups ORDER BY id LIMIT $1 OFFSET $2`
+
+	rows, err := repository.db.QueryContext(ctx, query, limitArg, offset)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []models.Group
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("row scan failed: %w", err)
+		}
+		groups = append(groups, *group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration failed: %w", err)
+	}
+	return groups, nil
+}
+
+func (repository *PostgresGroupRepository) Update(ctx context.Context, id int, update GroupUpdate) (*models.Group, error) {
+	var updates []string
+	var params []interface{}
+	counter := 1
+
+	set := func(column string, value interface{}) {
+		updates = append(updates, fmt.Sprintf("%s = $%d", column, counter))
+		params = append(params, value)
+		counter++
+	}
+
+	if update.Name != nil {
+		set("name", *update.Name)
+	}
+	if update.FormedYear != nil {
+		set("formed_year", *update.FormedYear)
+	}
+	if update.Country != nil {
+		set("country", *update.Country)
+	}
+	if update.Description != nil {
+		set("description", *update.Description)
+	}
+
+	if len(updates) == 0 {
+		return repository.Get(ctx, id)
+	}
+	updates = append(updates, "updated_at = NOW()")
+
+	query := fmt.Sprintf(
+		"UPDATE groups SET %s WHERE id = $%d RETURNING "+groupColumns,
+		strings.Join(updates, ", "),
+		counter,
+	)
+	params = append(params, id)
+
+	group, err := scanGroup(repository.db.QueryRowContext(ctx, query, params...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGroupNotFound
+	}
+	if isPQError(err, pqUniqueViolation) {
+		return nil, ErrGroupExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database update failed: %w", err)
+	}
+	return group, nil
+}
+
+func (repository *PostgresGroupRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM groups WHERE id = $1`
+	response, err := repository.db.ExecContext(ctx, query, id)
+	if isPQError(err, pqForeignKeyViolation) {
+		return ErrGroupInUse
+	}
+	if err != nil {
+		return fmt.Errorf("Database delete failed: %w", err)
+	}
+
+	rowsAffected, _ := response.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrGroupNotFound
+	}
+
+	log.Printf("Deleted group with id %d", id)
+	return nil
+}
+
+func (repository *PostgresGroupRepository) Resolve(ctx context.Context, name string) (*models.Group, error) {
+	// The no-op update makes RETURNING yield the existing row on conflict.
+	query := `INSERT INTO groups (name) VALUES ($1)
+			  ON CONFLICT ((LOWER(TRIM(name)))) DO UPDATE SET name = groups.name
+			  RETURNING ` + groupColumns
+
+	group, err := scanGroup(repository.db.QueryRowContext(ctx, query, name))
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return group, nil
+}
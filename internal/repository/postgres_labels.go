@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"testForWork/internal/models"
+)
+
+// labelTables names the tables behind one kind of label.
+type labelTables struct {
+	labels string
+	links  string
+	column string
+}
+
+var (
+	genreTables = labelTables{labels: "genres", links: "song_genres", column: "genre_id"}
+	tagTables   = labelTables{labels: "tags", links: "song_tags", column: "tag_id"}
+)
+
+// namesOf is an expression with the sorted label names of the song.
+func (tables labelTables) namesOf(songID string) string {
+	return fmt.Sprintf(
+		`ARRAY(SELECT l.name FROM %s link JOIN %s l ON l.id = link.%s WHERE link.song_id = %s ORDER BY l.name)`,
+		tables.links, tables.labels, tables.column, songID,
+	)
+}
+
+// countOf is an expression with the number of labels of the song that are
+// listed in the text array parameter.
+func (tables labelTables) countOf(songID, names string) string {
+	return fmt.Sprintf(
+		`(SELECT COUNT(*) FROM %s link JOIN %s l ON l.id = link.%s WHERE link.song_id = %s AND l.name = ANY(%s::text[]))`,
+		tables.links, tables.labels, tables.column, songID, names,
+	)
+}
+
+type PostgresLabelRepository struct {
+	db     *sql.DB
+	tables labelTables
+}
+
+func NewPostgresGenreRepository(db *sql.DB) *PostgresLabelRepository {
+	return &PostgresLabelRepository{db: db, tables: genreTables}
+}
+
+func NewPostgresTagRepository(db *sql.DB) *PostgresLabelRepository {
+	return &PostgresLabelRepository{db: db, tables: tagTables}
+}
+
+func (repository *PostgresLabelRepository) List(ctx context.Context) ([]models.Label, error) {
+	query := fmt.Sprintf(
+		`SELECT l.name, COUNT(link.song_id) FROM %s l LEFT JOIN %s link ON link.%s = l.id GROUP BY l.id ORDER BY l.name`,
+		repository.tables.labels, repository.tables.links, repository.tables.column,
+	)
+
+	rows, err := repository.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	labels := []models.Label{}
+	for rows.Next() {
+		var label models.Label
+		if err := rows.Scan(&label.Name, &label.SongCount); err != nil {
+			return nil, fmt.Errorf("row scan failed: %w", err)
+		}
+		labels = append(labels, label)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration failed: %w", err)
+	}
+	return labels, nil
+}
+
+func (repository *PostgresLabelRepository) Attach(ctx context.Context, songID int, names []string) error {
+	tx, err := repository.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s (name) SELECT UNNEST($1::text[]) ON CONFLICT (name) DO NOTHING`,
+		repository.tables.labels,
+	), pq.Array(names))
+	if err != nil {
+		return fmt.Errorf("database insert failed: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s (song_id, %s) SELECT $1, id FROM %s WHERE name = ANY($2::text[]) ON CONFLICT DO NOTHING`,
+		repository.tables.links, repository.tables.column, repository.tables.labels,
+	), songID, pq.Array(names))
+	if isPQError(err, pqForeignKeyViolation) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("database insert failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (repository *PostgresLabelRepository) Detach(ctx context.Context, songID int, name string) error {
+	var exists bool
+	err := repository.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM songs WHERE id = $1)`, songID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("database query failed: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	_, err = repository.db.ExecContext(ctx, fmt.Sprintf(
+		`DELETE FROM %s WHERE song_id = $1 AND %s = (SELECT id FROM %s WHERE name = $2)`,
+		repository.tables.links, repository.tables.column, repository.tables.labels,
+	), songID, name)
+	if err != nil {
+		return fmt.Errorf("Database delete failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"testForWork/internal/models"
+)
+
+// queryBuilder collects the WHERE conditions of a query together with their
+// parameters, so only the filters that are set end up in the query.
+type queryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// param adds a parameter and returns its placeholder.
+func (builder *queryBuilder) param(value interface{}) string {
+	builder.args = append(builder.args, value)
+	return fmt.Sprintf("$%d", len(builder.args))
+}
+
+// where adds a condition, every %s in it is replaced by the placeholder of
+// the next value.
+func (builder *queryBuilder) where(condition string, values ...interface{}) {
+	placeholders := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = builder.param(value)
+	}
+	builder.conditions = append(builder.conditions, fmt.Sprintf(condition, placeholders...))
+}
+
+// clause returns the WHERE clause, empty when there are no conditions.
+func (builder *queryBuilder) clause() string {
+	if len(builder.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(builder.conditions, "\n\t\t  AND ")
+}
+
+// presence is a condition on a text column being empty or not.
+func presence(column string, present bool) string {
+	if present {
+		return column + " <> ''"
+	}
+	return column + " = ''"
+}
+
+// songConditions adds the filters of the query to the builder and returns
+// the score expression of the fuzzy mode, which shares the name parameters.
+func songConditions(builder *queryBuilder, filter SongQuery) string {
+	var scores []string
+	if filter.GroupID != 0 {
+		builder.where("s.group_id = %s", filter.GroupID)
+	}
+	if filter.AlbumID != 0 {
+		builder.where("s.album_id = %s", filter.AlbumID)
+	}
+	if filter.Group != "" {
+		param := builder.param(nameArg(filter.Group, filter.NameMatch))
+		builder.where(nameCondition("g.name", param, filter.NameMatch))
+		scores = append(scores, fmt.Sprintf("word_similarity(%s, g.name)", param))
+	}
+	if filter.Song != "" {
+		param := builder.param(nameArg(filter.Song, filter.NameMatch))
+		builder.where(nameCondition("s.song_name", param, filter.NameMatch))
+		scores = append(scores, fmt.Sprintf("word_similarity(%s, s.song_name)", param))
+	}
+	if filter.ReleasedFrom != nil {
+		builder.where(releaseDateEnd+" > %s::date", *filter.ReleasedFrom)
+	}
+	if filter.ReleasedTo != nil {
+		builder.where("s.release_date < %s::date", *filter.ReleasedTo)
+	}
+	if filter.CreatedFrom != nil {
+		builder.where("s.created_at >= %s", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		builder.where("s.created_at < %s", *filter.CreatedTo)
+	}
+	if filter.UpdatedSince != nil {
+		builder.where("s.updated_at >= %s", *filter.UpdatedSince)
+	}
+	if filter.HasLink != nil {
+		builder.where(presence("s.link", *filter.HasLink))
+	}
+	if filter.HasText != nil {
+		builder.where(presence("s.text", *filter.HasText))
+	}
+	if filter.EnrichmentStatus != "" {
+		builder.where("s.enrichment_status = %s", filter.EnrichmentStatus)
+	}
+	if filter.EnrichmentDue != nil {
+		builder.where("(s.enrichment_retry_at IS NULL OR s.enrichment_retry_at <= %s)", *filter.EnrichmentDue)
+	}
+	labelConditions(builder, filter)
+	if filter.Keyset != nil {
+		keysetCondition(builder, filter.Order, filter.Keyset)
+	}
+
+	if filter.NameMatch != models.NameMatchFuzzy || len(scores) == 0 {
+		return "NULL::real"
+	}
+	return fmt.Sprintf("(%s) / %d", strings.Join(scores, " + "), len(scores))
+}
+
+// labelConditions requires every listed genre and tag, or at least one of
+// them with MatchAny.
+func labelConditions(builder *queryBuilder, filter SongQuery) {
+	var counts []string
+	if len(filter.Genres) > 0 {
+		counts = append(counts, genreTables.countOf("s.id", builder.param(textArray(filter.Genres))))
+	}
+	if len(filter.Tags) > 0 {
+		counts = append(counts, tagTables.countOf("s.id", builder.param(textArray(filter.Tags))))
+	}
+	if len(counts) == 0 {
+		return
+	}
+
+	if filter.MatchAny {
+		builder.where("(" + strings.Join(counts, " + ") + ") > 0")
+		return
+	}
+	if len(filter.Genres) > 0 {
+		builder.where(counts[0]+" = %s", len(filter.Genres))
+		counts = counts[1:]
+	}
+	if len(filter.Tags) > 0 {
+		builder.where(counts[0]+" = %s", len(filter.Tags))
+	}
+}
+
+// keysetCondition selects the songs following the keyset song in the
+// order, or preceding it, comparing the sort fields one after another like
+// a row comparison that also knows missing values sort last.
+func keysetCondition(builder *queryBuilder, order []SongOrder, keyset *SongKeyset) {
+	var alternatives, equal []string
+	compare := func(field string, desc bool, value interface{}) {
+		column := sortColumns[field]
+		nullable := field == models.SortReleaseDate || field == models.SortTrackNumber
+		if value == nil {
+			// only present values precede a missing one, none follow it
+			if keyset.Before {
+				alternatives = append(alternatives, strings.Join(append(equal, column+" IS NOT NULL"), " AND "))
+			}
+			equal = append(equal, column+" IS NULL")
+			return
+		}
+
+		param := fmt.Sprintf(sortParams[field], builder.param(value))
+		operator := ">"
+		if desc != keyset.Before {
+			operator = "<"
+		}
+		ahead := column + " " + operator + " " + param
+		if nullable && !keyset.Before {
+			ahead = "(" + ahead + " OR " + column + " IS NULL)"
+		}
+		alternatives = append(alternatives, strings.Join(append(equal, ahead), " AND "))
+		equal = append(equal, column+" = "+param)
+	}
+
+	for i, field := range order {
+		if _, ok := sortColumns[field.Field]; ok && i < len(keyset.Values) {
+			compare(field.Field, field.Desc, keyset.Values[i])
+		}
+	}
+	compare(models.SortID, false, keyset.ID)
+	builder.where("((" + strings.Join(alternatives, ") OR (") + "))")
+}
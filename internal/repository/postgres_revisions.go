@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testForWork/internal/models"
+)
+
+type PostgresTextRevisionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTextRevisionRepository(db *sql.DB) *PostgresTextRevisionRepository {
+	return &PostgresTextRevisionRepository{db: db}
+}
+
+func (repository *PostgresTextRevisionRepository) List(ctx context.Context, songID, limit, offset int) ([]models.TextRevision, error) {
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	query := `SELECT revision, author, comment, created_at
+		FROM song_text_revisions WHERE song_id = $1
+		ORDER BY revision DESC LIMIT $2 OFFSET $3`
+
+	rows, err := repository.db.QueryContext(ctx, query, songID, limitArg, offset)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.TextRevision{}
+	for rows.Next() {
+		var revision models.TextRevision
+		if err := rows.Scan(&revision.Revision, &revision.Author, &revision.Comment, &revision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("row scan failed: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration failed: %w", err)
+	}
+	return revisions, nil
+}
+
+func (repository *PostgresTextRevisionRepository) Get(ctx context.Context, songID, number int) (*models.TextRevision, error) {
+	query := `SELECT revision, text, author, comment, created_at
+		FROM song_text_revisions WHERE song_id = $1 AND revision = $2`
+
+	var revision models.TextRevision
+	err := repository.db.QueryRowContext(ctx, query, songID, number).Scan(
+		&revision.Revision, &revision.Text, &revision.Author, &revision.Comment, &revision.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	return &revision, nil
+}
+
+// recordRevision adds a revision with the text unless it equals the latest
+// one; a song without revisions counts as having an empty text. The caller
+// has locked the song row, so revision numbers do not race.
+func recordRevision(ctx context.Context, tx *sql.Tx, songID int, text string, note models.RevisionNote) error {
+	query := `INSERT INTO song_text_revisions (song_id, revision, text, author, comment)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4
+		FROM song_text_revisions WHERE song_id = $1
+		HAVING COALESCE((ARRAY_AGG(text ORDER BY revision DESC))[1], '') <> $2`
+
+	if _, err := tx.ExecContext(ctx, query, songID, text, note.Author, note.Comment); err != nil {
+		return fmt.Errorf("database insert failed: %w", err)
+	}
+	return nil
+}
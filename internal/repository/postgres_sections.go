@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"testForWork/internal/models"
+)
+
+type PostgresSectionRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSectionRepository(db *sql.DB) *PostgresSectionRepository {
+	return &PostgresSectionRepository{db: db}
+}
+
+func (repository *PostgresSectionRepository) List(ctx context.Context, songID int) ([]models.Section, error) {
+	query := `SELECT position, type, ordinal, label, lines, repeat_of
+		FROM song_sections WHERE song_id = $1 ORDER BY position`
+
+	rows, err := repository.db.QueryContext(ctx, query, songID)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	sections := []models.Section{}
+	for rows.Next() {
+		var section models.Section
+		err := rows.Scan(
+			&section.Position, &section.Type, &section.Ordinal, &section.Label,
+			pq.Array(&section.Lines), &section.RepeatOf,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("row scan failed: %w", err)
+		}
+		sections = append(sections, section)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration failed: %w", err)
+	}
+	return sections, nil
+}
+
+func (repository *PostgresSectionRepository) Replace(ctx context.Context, songID int, text string, sections []models.Section) (bool, error) {
+	tx, err := repository.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locks the song so a concurrent text update waits for the sections.
+	var current string
+	err = tx.QueryRowContext(ctx, `SELECT text FROM songs WHERE id = $1 FOR UPDATE`, songID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("database query failed: %w", err)
+	}
+	if current != text {
+		return false, nil
+	}
+
+	if err := replaceSections(ctx, tx, songID, sections); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (repository *PostgresSectionRepository) Unparsed(ctx context.Context) ([]int, error) {
+	query := `SELECT s.id FROM songs s
+		WHERE TRIM(s.text) <> '' AND NOT EXISTS (SELECT 1 FROM song_sections ss WHERE ss.song_id = s.id)
+		ORDER BY s.id`
+
+	rows, err := repository.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("row scan failed: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration failed: %w", err)
+	}
+	return ids, nil
+}
+
+// replaceSections swaps the stored sections of the song within the
+// transaction. Repeats come after the sections they point at, so every
+// row finds its original already inserted.
+func replaceSections(ctx context.Context, tx *sql.Tx, songID int, sections []models.Section) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM song_sections WHERE song_id = $1`, songID); err != nil {
+		return fmt.Errorf("database delete failed: %w", err)
+	}
+
+	for _, section := range sections {
+		var lines interface{}
+		if section.RepeatOf == nil {
+			lines = textArray(section.Lines)
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO song_sections (song_id, position, type, ordinal, label, lines, repeat_of)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			songID, section.Position, section.Type, section.Ordinal, section.Label, lines, section.RepeatOf,
+		)
+		if err != nil {
+			return fmt.Errorf("database insert failed: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testForWork/internal/models"
+)
+
+type PostgresSyncedLyricsRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresSyncedLyricsRepository(db *sql.DB) *PostgresSyncedLyricsRepository {
+	return &PostgresSyncedLyricsRepository{db: db}
+}
+
+func (repository *PostgresSyncedLyricsRepository) Get(ctx context.Context, songID int) (*models.SyncedLyrics, error) {
+	var tags, lines []byte
+	err := repository.db.QueryRowContext(ctx,
+		`SELECT tags, lines FROM song_synced_lyrics WHERE song_id = $1`, songID,
+	).Scan(&tags, &lines)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSyncedLyricsNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+
+	lyrics := &models.SyncedLyrics{}
+	if err := json.Unmarshal(tags, &lyrics.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode synced lyrics tags: %w", err)
+	}
+	if err := json.Unmarshal(lines, &lyrics.Lines); err != nil {
+		return nil, fmt.Errorf("failed to decode synced lyrics lines: %w", err)
+	}
+	return lyrics, nil
+}
+
+// replaceSyncedLyrics swaps the synced lyrics of the song within the
+// transaction, nil lyrics only remove the stored ones.
+func replaceSyncedLyrics(ctx context.Context, tx *sql.Tx, songID int, lyrics *models.SyncedLyrics) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM song_synced_lyrics WHERE song_id = $1`, songID); err != nil {
+		return fmt.Errorf("database delete failed: %w", err)
+	}
+	if lyrics == nil {
+		return nil
+	}
+
+	tags, err := json.Marshal(lyrics.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode synced lyrics tags: %w", err)
+	}
+	lines, err := json.Marshal(lyrics.Lines)
+	if err != nil {
+		return fmt.Errorf("failed to encode synced lyrics lines: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO song_synced_lyrics (song_id, tags, lines) VALUES ($1, $2, $3)`,
+		songID, string(tags), string(lines),
+	)
+	if err != nil {
+		return fmt.Errorf("database insert failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testForWork/internal/models"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("song not found")
+	ErrSongExists    = errors.New("song already exists")
+	ErrGroupNotFound = errors.New("group not found")
+	ErrGroupExists   = errors.New("group already exists")
+	ErrGroupInUse    = errors.New("group has songs or albums")
+	ErrAlbumNotFound = errors.New("album not found")
+	ErrTrackGroup    = errors.New("song belongs to another group than the album")
+
+	ErrSyncedLyricsNotFound = errors.New("synced lyrics not found")
+	ErrRevisionNotFound     = errors.New("text revision not found")
+)
+
+// Repositories bundles the stores the service works with.
+type Repositories struct {
+	Songs     SongRepository
+	Groups    GroupRepository
+	Albums    AlbumRepository
+	Genres    LabelRepository
+	Tags      LabelRepository
+	Sections  SectionRepository
+	Synced    SyncedLyricsRepository
+	Revisions TextRevisionRepository
+}
+
+// SongRepository stores songs. Implementations must be safe for concurrent use.
+type SongRepository interface {
+	// Create returns ErrSongExists when the group already has a song of the
+	// same name, ignoring case and surrounding spaces.
+	Create(ctx context.Context, song models.Song) (*models.Song, error)
+	// Get returns ErrNotFound when there is no song with the id.
+	Get(ctx context.Context, id int) (*models.Song, error)
+	// FindByName returns the song of the group with the name, compared like
+	// Create does, or ErrNotFound.
+	FindByName(ctx context.Context, groupID int, name string) (*models.Song, error)
+	// List returns the matching songs in the query order.
+	List(ctx context.Context, query SongQuery) ([]models.Song, error)
+	// Count returns how many songs match the query, ignoring its order and
+	// paging.
+	Count(ctx context.Context, query SongQuery) (int, error)
+	// Search returns the songs matching the text search, best ranked first.
+	Search(ctx context.Context, search SongSearch) ([]models.SongSearchResult, error)
+	// Update returns ErrNotFound when there is no song with the id or it
+	// does not meet the IfEnrichmentStatus condition, ErrSongExists when
+	// the new group and name belong to another song, and ErrTrackGroup when
+	// the song is moved to another group than the group of its album.
+	Update(ctx context.Context, id int, update SongUpdate) (*models.Song, error)
+	Delete(ctx context.Context, id int) error
+	// RecordEnrichmentFailure counts a failed attempt of a pending song and
+	// marks the song failed once it made maxAttempts attempts, or schedules
+	// the next attempt at retryAt otherwise.
+	RecordEnrichmentFailure(ctx context.Context, id int, cause string, maxAttempts int, retryAt time.Time) error
+}
+
+// GroupRepository stores groups. Group names are unique ignoring case and
+// surrounding spaces.
+type GroupRepository interface {
+	// Create returns ErrGroupExists when the name is taken.
+	Create(ctx context.Context, group models.Group) (*models.Group, error)
+	// Get returns ErrGroupNotFound when there is no group with the id.
+	Get(ctx context.Context, id int) (*models.Group, error)
+	// List returns groups ordered by id, a limit of zero means no limit.
+	List(ctx context.Context, limit, offset int) ([]models.Group, error)
+	// Update returns ErrGroupExists when renaming to a taken name.
+	Update(ctx context.Context, id int, update GroupUpdate) (*models.Group, error)
+	// Delete returns ErrGroupInUse while songs of the group exist.
+	Delete(ctx context.Context, id int) error
+	// Resolve returns the group with the name, creating it if needed.
+	Resolve(ctx context.Context, name string) (*models.Group, error)
+}
+
+// GroupUpdate lists the fields to change, nil fields are left as they are.
+type GroupUpdate struct {
+	Name        *string
+	FormedYear  *int
+	Country     *string
+	Description *string
+}
+
+// AlbumRepository stores albums and their tracklists.
+type AlbumRepository interface {
+	// Create returns ErrGroupNotFound when the group does not exist.
+	Create(ctx context.Context, album models.Album) (*models.Album, error)
+	// Get returns ErrAlbumNotFound when there is no album with the id.
+	Get(ctx context.Context, id int) (*models.Album, error)
+	// List returns albums ordered by id, of one group when groupID is not
+	// zero. A limit of zero means no limit.
+	List(ctx context.Context, groupID, limit, offset int) ([]models.Album, error)
+	// Update returns ErrTrackGroup when a new group is set while the album
+	// has tracks, which all belong to its current group.
+	Update(ctx context.Context, id int, update AlbumUpdate) (*models.Album, error)
+	// Delete removes the album, its songs stay without an album.
+	Delete(ctx context.Context, id int) error
+	// SetTracks replaces the tracklist: songIDs[i] becomes track i+1, songs
+	// left out of the list lose the album. Songs on another album move to
+	// this one. Returns ErrNotFound when a song does not exist and
+	// ErrTrackGroup when it belongs to another group than the album.
+	SetTracks(ctx context.Context, id int, songIDs []int) error
+}
+
+// AlbumUpdate lists the fields to change, nil fields are left as they are.
+type AlbumUpdate struct {
+	GroupID *int
+	Title   *string
+	// SetReleaseDate replaces the release date with ReleaseDate, which may
+	// be nil to clear it.
+	SetReleaseDate bool
+	ReleaseDate    *models.ReleaseDate
+	Type           *string
+}
+
+// LabelRepository stores the genres or the tags of songs. Names are expected
+// to be normalized by the caller.
+type LabelRepository interface {
+	// List returns every label with its song count, ordered by name.
+	List(ctx context.Context) ([]models.Label, error)
+	// Attach adds the labels to the song, creating unknown ones. Returns
+	// ErrNotFound when the song does not exist.
+	Attach(ctx context.Context, songID int, names []string) error
+	// Detach removes the label from the song, doing nothing when the song
+	// does not carry it. Returns ErrNotFound when the song does not exist.
+	Detach(ctx context.Context, songID int, name string) error
+}
+
+// SectionRepository stores the lyrics sections of songs. SongRepository.Update
+// replaces them too when it changes the text.
+type SectionRepository interface {
+	// List returns the sections of the song ordered by position, none for
+	// an unknown song.
+	List(ctx context.Context, songID int) ([]models.Section, error)
+	// Replace stores the sections parsed from text unless the song text has
+	// changed meanwhile, reporting whether it did. Returns ErrNotFound when
+	// the song does not exist.
+	Replace(ctx context.Context, songID int, text string, sections []models.Section) (bool, error)
+	// Unparsed returns the ids of songs that have text but no sections.
+	Unparsed(ctx context.Context) ([]int, error)
+}
+
+// SyncedLyricsRepository reads the synced lyrics of songs, which
+// SongRepository.Update stores along with the text.
+type SyncedLyricsRepository interface {
+	// Get returns ErrSyncedLyricsNotFound when the song has no synced lyrics.
+	Get(ctx context.Context, songID int) (*models.SyncedLyrics, error)
+}
+
+// TextRevisionRepository reads the text history of songs, which
+// SongRepository.Update records.
+type TextRevisionRepository interface {
+	// List returns the revisions of the song newest first, without their
+	// text. A limit of zero means no limit.
+	List(ctx context.Context, songID, limit, offset int) ([]models.TextRevision, error)
+	// Get returns ErrRevisionNotFound when the song has no such revision.
+	Get(ctx context.Context, songID, revision int) (*models.TextRevision, error)
+}
+
+// SongQuery filters List. Empty fields match every song.
+type SongQuery struct {
+	GroupID int
+	AlbumID int
+	// Group and Song match the names ignoring case in the NameMatch mode,
+	// exact when it is empty. Fuzzy matches are ordered by similarity.
+	Group     string
+	Song      string
+	NameMatch string
+	// ReleasedFrom and ReleasedTo select songs whose release period
+	// overlaps [ReleasedFrom, ReleasedTo), either bound may be nil. Songs
+	// without a release date never match.
+	ReleasedFrom *time.Time
+	ReleasedTo   *time.Time
+	// CreatedFrom, CreatedTo and UpdatedSince bound created_at to
+	// [CreatedFrom, CreatedTo) and updated_at from UpdatedSince on.
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	UpdatedSince *time.Time
+	// HasLink and HasText select songs with or without a link or lyrics.
+	HasLink          *bool
+	HasText          *bool
+	EnrichmentStatus string
+	// EnrichmentDue selects songs with no enrichment retry due after it.
+	EnrichmentDue *time.Time
+	// Genres and Tags select songs carrying all of them, or any of them
+	// when MatchAny is set.
+	Genres   []string
+	Tags     []string
+	MatchAny bool
+	// Order lists the sort fields, ties are broken by id. Songs are ordered
+	// by id, or by score in the fuzzy mode, when it is empty.
+	Order []SongOrder
+	// Keyset continues the listing from a song, Offset is ignored with it.
+	Keyset *SongKeyset
+	// Limit of zero means no limit.
+	Limit  int
+	Offset int
+}
+
+// SongKeyset stands for a song by its sort values, to list the songs that
+// follow it, or precede it with Before, in the query order. Songs preceding
+// it are still returned in the query order.
+type SongKeyset struct {
+	// Values holds SortValue of the song for every field of the query Order.
+	Values []interface{}
+	ID     int
+	Before bool
+}
+
+// SortValue returns what the song is sorted by for the field: a string, an
+// int, a time.Time, or nil when the song has no release date or track.
+// Strings are compared ignoring case, release dates by their SortKey.
+func SortValue(song models.Song, field string) interface{} {
+	switch field {
+	case models.SortSong:
+		return song.Song
+	case models.SortGroup:
+		return song.Group
+	case models.SortReleaseDate:
+		if song.ReleaseDate == nil {
+			return nil
+		}
+		return song.ReleaseDate.SortKey()
+	case models.SortTrackNumber:
+		if song.TrackNumber == nil {
+			return nil
+		}
+		return *song.TrackNumber
+	case models.SortCreatedAt:
+		return song.CreatedAt
+	case models.SortUpdatedAt:
+		return song.UpdatedAt
+	default:
+		return song.ID
+	}
+}
+
+// SongSearch is a full-text search over song names, group names and lyrics.
+type SongSearch struct {
+	// Text is a web search style query: words, "quoted phrases", or and -word.
+	Text string
+	// Limit of zero means no limit.
+	Limit  int
+	Offset int
+}
+
+// SongOrder sorts songs by one of models.SongSortFields. Songs without a
+// release date or a track number come last in either direction.
+type SongOrder struct {
+	Field string
+	Desc  bool
+}
+
+// SongUpdate lists the fields to change, nil fields are left as they are.
+type SongUpdate struct {
+	GroupID *int
+	Song    *string
+	// SetReleaseDate replaces the release date with ReleaseDate, which may
+	// be nil to clear it.
+	SetReleaseDate bool
+	ReleaseDate    *models.ReleaseDate
+	Text           *string
+	Link           *string
+	// Sections replace the stored sections of the song along with Text and
+	// are ignored without it.
+	Sections []models.Section
+	// SyncedLyrics replace the stored synced lyrics along with Text. A Text
+	// set without them removes the stored synced lyrics only when it differs
+	// from the current text, which they no longer match then.
+	SyncedLyrics *models.SyncedLyrics
+	// Revision describes the text revision recorded when Text differs from
+	// the latest one.
+	Revision models.RevisionNote
+	// Sources are merged into the stored ones.
+	Sources models.Sources
+	// PreserveSource leaves release date, text and link as they are when
+	// their stored source is PreserveSource, along with their sources.
+	PreserveSource string
+	// EnrichmentStatus also clears the scheduled enrichment retry.
+	EnrichmentStatus *string
+	EnrichmentError  *string
+	CountAttempt     bool
+	// IfEnrichmentStatus applies the update only to songs in that status.
+	IfEnrichmentStatus string
+}
+
+// preserveFields drops from the update the detail fields whose stored
+// source is the update's PreserveSource.
+func preserveFields(update SongUpdate, stored models.Sources) SongUpdate {
+	if update.PreserveSource == "" {
+		return update
+	}
+
+	sources := models.Sources{}
+	for field, source := range update.Sources {
+		if stored[field] != update.PreserveSource {
+			sources[field] = source
+		}
+	}
+	update.Sources = sources
+
+	if stored[models.FieldReleaseDate] == update.PreserveSource {
+		update.SetReleaseDate, update.ReleaseDate = false, nil
+	}
+	if stored[models.FieldText] == update.PreserveSource {
+		update.Text, update.Sections, update.SyncedLyrics = nil, nil, nil
+	}
+	if stored[models.FieldLink] == update.PreserveSource {
+		update.Link = nil
+	}
+	return update
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+)
+
+func validateAlbumType(albumType string) error {
+	if !slices.Contains(models.AlbumTypes, albumType) {
+		return fmt.Errorf("%w: album type must be one of %s", ErrInvalidInput, strings.Join(models.AlbumTypes, ", "))
+	}
+	return nil
+}
+
+func (service *Service) CreateAlbum(ctx context.Context, req models.AlbumRequest) (*models.Album, error) {
+	group := strings.TrimSpace(req.Group)
+	title := strings.TrimSpace(req.Title)
+	if group == "" || title == "" {
+		return nil, fmt.Errorf("%w: group and title cannot be empty", ErrInvalidInput)
+	}
+	albumType := strings.ToLower(req.Type)
+	if albumType == "" {
+		albumType = models.AlbumLP
+	}
+	if err := validateAlbumType(albumType); err != nil {
+		return nil, err
+	}
+	releaseDate, err := service.dates.Parse(req.ReleaseDate)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.Albums)
+	defer cancel()
+
+	resolved, err := service.groups.Resolve(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	album, err := service.albums.Create(ctx, models.Album{
+		GroupID:     resolved.ID,
+		Title:       title,
+		ReleaseDate: releaseDate,
+		Type:        albumType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Created new album: %d", album.ID)
+	return album, nil
+}
+
+func (service *Service) GetAlbums(ctx context.Context, groupID, page, limit int) ([]models.Album, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.Albums)
+	defer cancel()
+
+	return service.albums.List(ctx, groupID, limit, (page-1)*limit)
+}
+
+func (service *Service) GetAlbum(ctx context.Context, id int) (*models.Album, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.Albums)
+	defer cancel()
+
+	return service.albums.Get(ctx, id)
+}
+
+func (service *Service) UpdateAlbum(ctx context.Context, id int, req models.AlbumUpdateRequest) (*models.Album, error) {
+	var update repository.AlbumUpdate
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, fmt.Errorf("%w: title cannot be empty", ErrInvalidInput)
+		}
+		update.Title = &title
+	}
+	if req.Type != nil {
+		albumType := strings.ToLower(*req.Type)
+		if err := validateAlbumType(albumType); err != nil {
+			return nil, err
+		}
+		update.Type = &albumType
+	}
+	if req.ReleaseDate != nil {
+		releaseDate, err := service.dates.Parse(*req.ReleaseDate)
+		if err != nil {
+			return nil, err
+		}
+		update.SetReleaseDate, update.ReleaseDate = true, releaseDate
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.Albums)
+	defer cancel()
+
+	if req.Group != nil {
+		name := strings.TrimSpace(*req.Group)
+		if name == "" {
+			return nil, fmt.Errorf("%w: group name cannot be empty", ErrInvalidInput)
+		}
+		group, err := service.groups.Resolve(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		update.GroupID = &group.ID
+	}
+
+	return service.albums.Update(ctx, id, update)
+}
+
+// DeleteAlbum deletes the album only, its songs stay without an album.
+func (service *Service) DeleteAlbum(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, service.timeouts.Albums)
+	defer cancel()
+
+	return service.albums.Delete(ctx, id)
+}
+
+var trackOrder = []repository.SongOrder{{Field: models.SortTrackNumber}}
+
+// GetAlbumTracks returns the songs of the album in tracklist order.
+func (service *Service) GetAlbumTracks(ctx context.Context, id int) ([]models.Song, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.Albums)
+	defer cancel()
+
+	if _, err := service.albums.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return service.songs.List(ctx, repository.SongQuery{AlbumID: id, Order: trackOrder})
+}
+
+// SetAlbumTracks replaces the tracklist and returns it.
+func (service *Service) SetAlbumTracks(ctx context.Context, id int, req models.AlbumTracksRequest) ([]models.Song, error) {
+	seen := make(map[int]bool, len(req.SongIDs))
+	for _, songID := range req.SongIDs {
+		if seen[songID] {
+			return nil, fmt.Errorf("%w: song %d is listed twice", ErrInvalidInput, songID)
+		}
+		seen[songID] = true
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.Albums)
+	defer cancel()
+
+	if err := service.albums.SetTracks(ctx, id, req.SongIDs); err != nil {
+		return nil, err
+	}
+	log.Printf("Set %d tracks of album %d", len(req.SongIDs), id)
+	return service.songs.List(ctx, repository.SongQuery{AlbumID: id, Order: trackOrder})
+}
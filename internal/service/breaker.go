@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"testForWork/internal/models"
+	"time"
+)
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calling an upstream after repeated failures and lets a
+// single probe through once the open timeout has passed. Every state change
+// starts a new generation; a call reports its outcome with the generation it
+// was allowed in, and outcomes of calls from an older one are ignored, so a
+// slow call started before the breaker opened cannot close it again.
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	name        string
+	threshold   int
+	openTimeout time.Duration
+	state       string
+	generation  uint64
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	lastError   string
+	trips       int
+}
+
+func NewCircuitBreaker(name string, threshold int, openTimeout time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		name:        name,
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		state:       BreakerClosed,
+	}
+}
+
+// Allow reports whether a call may proceed and returns the generation to
+// report its outcome with. While the breaker is half-open only one probe is
+// allowed at a time.
+func (breaker *CircuitBreaker) Allow() (uint64, error) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	switch breaker.state {
+	case BreakerOpen:
+		if time.Since(breaker.openedAt) < breaker.openTimeout {
+			return 0, ErrCircuitOpen
+		}
+		breaker.setState(BreakerHalfOpen)
+		breaker.probing = true
+	case BreakerHalfOpen:
+		if breaker.probing {
+			return 0, ErrCircuitOpen
+		}
+		breaker.probing = true
+	}
+	return breaker.generation, nil
+}
+
+func (breaker *CircuitBreaker) Success(generation uint64) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	if generation != breaker.generation {
+		return
+	}
+	breaker.failures = 0
+	breaker.probing = false
+	breaker.setState(BreakerClosed)
+}
+
+func (breaker *CircuitBreaker) Failure(generation uint64, err error) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	if generation != breaker.generation {
+		return
+	}
+	breaker.failures++
+	breaker.probing = false
+	if err != nil {
+		breaker.lastError = err.Error()
+	}
+
+	if breaker.state == BreakerHalfOpen || breaker.failures >= breaker.threshold {
+		if breaker.state != BreakerOpen {
+			breaker.trips++
+		}
+		breaker.openedAt = time.Now()
+		breaker.setState(BreakerOpen)
+	}
+}
+
+// Cancel releases a call that ended without telling anything about the
+// upstream, such as one aborted by the caller.
+func (breaker *CircuitBreaker) Cancel(generation uint64) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	if generation == breaker.generation {
+		breaker.probing = false
+	}
+}
+
+func (breaker *CircuitBreaker) Status() *models.CircuitBreakerStatus {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	status := &models.CircuitBreakerStatus{
+		Name:                breaker.name,
+		State:               breaker.state,
+		ConsecutiveFailures: breaker.failures,
+		Threshold:           breaker.threshold,
+		Trips:               breaker.trips,
+		LastError:           breaker.lastError,
+	}
+	if breaker.state != BreakerClosed {
+		openedAt := breaker.openedAt
+		retryAt := openedAt.Add(breaker.openTimeout)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+func (breaker *CircuitBreaker) setState(state string) {
+	if breaker.state == state {
+		return
+	}
+	log.Printf("Circuit breaker %s: %s -> %s", breaker.name, breaker.state, state)
+	breaker.state = state
+	breaker.generation++
+}
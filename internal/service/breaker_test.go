@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errUpstream = errors.New("upstream failed")
+
+// openBreaker returns a breaker with a threshold of two that failed twice.
+func openBreaker(t *testing.T, openTimeout time.Duration) *CircuitBreaker {
+	t.Helper()
+	breaker := NewCircuitBreaker("test", 2, openTimeout)
+	for range 2 {
+		generation, err := breaker.Allow()
+		if err != nil {
+			t.Fatalf("Allow while closed: %v", err)
+		}
+		breaker.Failure(generation, errUpstream)
+	}
+	if state := breaker.Status().State; state != BreakerOpen {
+		t.Fatalf("state after the threshold = %s, want %s", state, BreakerOpen)
+	}
+	return breaker
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		// run drives the breaker and returns the state it should end in
+		run   func(t *testing.T) (*CircuitBreaker, string)
+		trips int
+	}{
+		{
+			name: "failures below the threshold keep it closed",
+			run: func(t *testing.T) (*CircuitBreaker, string) {
+				breaker := NewCircuitBreaker("test", 3, time.Minute)
+				for range 2 {
+					generation, _ := breaker.Allow()
+					breaker.Failure(generation, errUpstream)
+				}
+				return breaker, BreakerClosed
+			},
+		},
+		{
+			name: "a success resets the failures",
+			run: func(t *testing.T) (*CircuitBreaker, string) {
+				breaker := NewCircuitBreaker("test", 2, time.Minute)
+				generation, _ := breaker.Allow()
+				breaker.Failure(generation, errUpstream)
+				generation, _ = breaker.Allow()
+				breaker.Success(generation)
+				generation, _ = breaker.Allow()
+				breaker.Failure(generation, errUpstream)
+				return breaker, BreakerClosed
+			},
+		},
+		{
+			name: "the threshold opens it",
+			run: func(t *testing.T) (*CircuitBreaker, string) {
+				breaker := openBreaker(t, time.Minute)
+				if _, err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+					t.Errorf("Allow while open = %v, want ErrCircuitOpen", err)
+				}
+				return breaker, BreakerOpen
+			},
+			trips: 1,
+		},
+		{
+			name: "one probe after the timeout",
+			run: func(t *testing.T) (*CircuitBreaker, string) {
+				breaker := openBreaker(t, 0)
+				if _, err := breaker.Allow(); err != nil {
+					t.Fatalf("probe: %v", err)
+				}
+				if _, err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+					t.Errorf("second call during the probe = %v, want ErrCircuitOpen", err)
+				}
+				return breaker, BreakerHalfOpen
+			},
+			trips: 1,
+		},
+		{
+			name: "a successful probe closes it",
+			run: func(t *testing.T) (*CircuitBreaker, string) {
+				breaker := openBreaker(t, 0)
+				generation, _ := breaker.Allow()
+				breaker.Success(generation)
+				if _, err := breaker.Allow(); err != nil {
+					t.Errorf("Allow after closing: %v", err)
+				}
+				return breaker, BreakerClosed
+			},
+			trips: 1,
+		},
+		{
+			name: "a failed probe opens it again",
+			run: func(t *testing.T) (*CircuitBreaker, string) {
+				breaker := openBreaker(t, 0)
+				generation, _ := breaker.Allow()
+				breaker.Failure(generation, errUpstream)
+				return breaker, BreakerOpen
+			},
+			trips: 2,
+		},
+		{
+			name: "a cancelled probe lets another one through",
+			run: func(t *testing.T) (*CircuitBreaker, string) {
+				breaker := openBreaker(t, 0)
+				generation, _ := breaker.Allow()
+				breaker.Cancel(generation)
+				if _, err := breaker.Allow(); err != nil {
+					t.Errorf("Allow after the cancelled probe: %v", err)
+				}
+				return breaker, BreakerHalfOpen
+			},
+			trips: 1,
+		},
+		{
+			name: "a call from before opening cannot close it",
+			run: func(t *testing.T) (*CircuitBreaker, string) {
+				breaker := NewCircuitBreaker("test", 1, time.Minute)
+				slow, _ := breaker.Allow()
+				failed, _ := breaker.Allow()
+				breaker.Failure(failed, errUpstream)
+				breaker.Success(slow)
+				return breaker, BreakerOpen
+			},
+			trips: 1,
+		},
+		{
+			name: "a call from before the probe cannot decide it",
+			run: func(t *testing.T) (*CircuitBreaker, string) {
+				breaker := NewCircuitBreaker("test", 1, 0)
+				slow, _ := breaker.Allow()
+				failed, _ := breaker.Allow()
+				breaker.Failure(failed, errUpstream)
+				if _, err := breaker.Allow(); err != nil {
+					t.Fatalf("probe: %v", err)
+				}
+				breaker.Failure(slow, errUpstream)
+				breaker.Cancel(slow)
+				if _, err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+					t.Errorf("second call during the probe = %v, want ErrCircuitOpen", err)
+				}
+				return breaker, BreakerHalfOpen
+			},
+			trips: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			breaker, want := test.run(t)
+			status := breaker.Status()
+			if status.State != want {
+				t.Errorf("state = %s, want %s", status.State, want)
+			}
+			if status.Trips != test.trips {
+				t.Errorf("trips = %d, want %d", status.Trips, test.trips)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerStatus(t *testing.T) {
+	breaker := openBreaker(t, time.Minute)
+	status := breaker.Status()
+	if status.LastError != errUpstream.Error() || status.ConsecutiveFailures != 2 || status.Threshold != 2 {
+		t.Errorf("status = %+v", status)
+	}
+	if status.OpenedAt == nil || status.RetryAt == nil || status.RetryAt.Sub(*status.OpenedAt) != time.Minute {
+		t.Errorf("open status times = %v, %v", status.OpenedAt, status.RetryAt)
+	}
+}
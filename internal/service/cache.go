@@ -0,0 +1,263 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testForWork/internal/models"
+	"time"
+)
+
+// CachingDetailsProvider keeps recent provider answers in an LRU cache keyed
+// by group and song. Songs the provider does not know are cached too, for a
+// shorter time, so repeated lookups of missing songs do not hit the upstream.
+type CachingDetailsProvider struct {
+	provider    DetailsProvider
+	capacity    int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu           sync.Mutex
+	items        map[string]*list.Element
+	order        *list.List
+	hits         uint64
+	negativeHits uint64
+	misses       uint64
+	evictions    uint64
+}
+
+type cacheEntry struct {
+	key       string
+	group     string
+	song      string
+	detail    *models.SongDetail
+	notFound  bool
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+func NewCachingDetailsProvider(provider DetailsProvider, capacity int, ttl, negativeTTL time.Duration) *CachingDetailsProvider {
+	return &CachingDetailsProvider{
+		provider:    provider,
+		capacity:    capacity,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		items:       make(map[string]*list.Element),
+		order:       list.New(),
+	}
+}
+
+func (cache *CachingDetailsProvider) FetchDetails(ctx context.Context, group, song string) (*models.SongDetail, error) {
+	key := cacheKey(group, song)
+
+	if entry, ok := cache.lookup(key); ok {
+		if entry.notFound {
+			return nil, fmt.Errorf("%w (cached)", ErrDetailsNotFound)
+		}
+		detail := *entry.detail
+		return &detail, nil
+	}
+
+	return cache.fetch(ctx, key, group, song)
+}
+
+// Refresh skips the cached entry and asks the provider, replacing the entry
+// with the fresh answer.
+func (cache *CachingDetailsProvider) Refresh(ctx context.Context, group, song string) (*models.SongDetail, error) {
+	return cache.fetch(ctx, cacheKey(group, song), group, song)
+}
+
+// fetch asks the provider and caches its answer. Answers of fallback
+// providers only stand in for the upstream and drop the entry instead.
+func (cache *CachingDetailsProvider) fetch(ctx context.Context, key, group, song string) (*models.SongDetail, error) {
+	detail, err := cache.provider.FetchDetails(ctx, group, song)
+	if err != nil {
+		switch {
+		case errors.Is(err, errFallbackDetails):
+			cache.forget(key)
+		case errors.Is(err, ErrDetailsNotFound):
+			cache.store(key, group, song, nil, true)
+		}
+		return nil, err
+	}
+
+	if detail.Fallback {
+		cache.forget(key)
+		return detail, nil
+	}
+	stored := *detail
+	cache.store(key, group, song, &stored, false)
+	return detail, nil
+}
+
+// Unwrap returns the provider behind the cache.
+func (cache *CachingDetailsProvider) Unwrap() DetailsProvider {
+	return cache.provider
+}
+
+func (cache *CachingDetailsProvider) Stats() *models.DetailsCacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	stats := &models.DetailsCacheStats{
+		Size:         cache.order.Len(),
+		Capacity:     cache.capacity,
+		TTL:          cache.ttl.String(),
+		NegativeTTL:  cache.negativeTTL.String(),
+		Hits:         cache.hits,
+		NegativeHits: cache.negativeHits,
+		Misses:       cache.misses,
+		Evictions:    cache.evictions,
+	}
+	if lookups := cache.hits + cache.negativeHits + cache.misses; lookups > 0 {
+		stats.HitRatio = float64(cache.hits+cache.negativeHits) / float64(lookups)
+	}
+	return stats
+}
+
+// Entries lists live entries, most recently used first.
+func (cache *CachingDetailsProvider) Entries() []models.DetailsCacheEntry {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]models.DetailsCacheEntry, 0, cache.order.Len())
+	for element := cache.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*cacheEntry)
+		if now.After(entry.expiresAt) {
+			cache.remove(element)
+		} else {
+			entries = append(entries, models.DetailsCacheEntry{
+				Group:     entry.group,
+				Song:      entry.song,
+				NotFound:  entry.notFound,
+				Detail:    entry.detail,
+				StoredAt:  entry.storedAt,
+				ExpiresAt: entry.expiresAt,
+			})
+		}
+		element = next
+	}
+	return entries
+}
+
+// Purge drops cached entries: all of them when group is empty, all songs of
+// the group when song is empty, or a single group/song pair.
+func (cache *CachingDetailsProvider) Purge(group, song string) int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if group == "" {
+		purged := cache.order.Len()
+		cache.items = make(map[string]*list.Element)
+		cache.order.Init()
+		return purged
+	}
+
+	if song != "" {
+		element, ok := cache.items[cacheKey(group, song)]
+		if !ok {
+			return 0
+		}
+		cache.remove(element)
+		return 1
+	}
+
+	purged := 0
+	prefix := normalizeCacheKey(group) + "\x00"
+	for key, element := range cache.items {
+		if strings.HasPrefix(key, prefix) {
+			cache.remove(element)
+			purged++
+		}
+	}
+	return purged
+}
+
+func (cache *CachingDetailsProvider) lookup(key string) (*cacheEntry, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.items[key]
+	if !ok {
+		cache.misses++
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		cache.remove(element)
+		cache.misses++
+		return nil, false
+	}
+
+	cache.order.MoveToFront(element)
+	if entry.notFound {
+		cache.negativeHits++
+	} else {
+		cache.hits++
+	}
+	return entry, true
+}
+
+func (cache *CachingDetailsProvider) store(key, group, song string, detail *models.SongDetail, notFound bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	ttl := cache.ttl
+	if notFound {
+		ttl = cache.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	entry := &cacheEntry{
+		key:       key,
+		group:     group,
+		song:      song,
+		detail:    detail,
+		notFound:  notFound,
+		storedAt:  now,
+		expiresAt: now.Add(ttl),
+	}
+
+	if element, ok := cache.items[key]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.items[key] = cache.order.PushFront(entry)
+	for cache.order.Len() > cache.capacity {
+		cache.remove(cache.order.Back())
+		cache.evictions++
+	}
+}
+
+func (cache *CachingDetailsProvider) forget(key string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if element, ok := cache.items[key]; ok {
+		cache.remove(element)
+	}
+}
+
+func (cache *CachingDetailsProvider) remove(element *list.Element) {
+	entry := cache.order.Remove(element).(*cacheEntry)
+	delete(cache.items, entry.key)
+}
+
+func cacheKey(group, song string) string {
+	return normalizeCacheKey(group) + "\x00" + normalizeCacheKey(song)
+}
+
+func normalizeCacheKey(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+	"time"
+)
+
+// songCursor is the content of an opaque page cursor: the sort it was made
+// for and the sort values and id of the song it points at.
+type songCursor struct {
+	Sort   string            `json:"sort"`
+	Values []json.RawMessage `json:"values"`
+	ID     int               `json:"id"`
+}
+
+// formatSort writes the order back in the form of the sort parameter.
+func formatSort(order []repository.SongOrder) string {
+	fields := make([]string, len(order))
+	for i, field := range order {
+		fields[i] = field.Field
+		if field.Desc {
+			fields[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func encodeCursor(song models.Song, order []repository.SongOrder) string {
+	cursor := songCursor{Sort: formatSort(order), ID: song.ID}
+	for _, field := range order {
+		value, _ := json.Marshal(repository.SortValue(song, field.Field))
+		cursor.Values = append(cursor.Values, value)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor made by encodeCursor for the same order.
+func decodeCursor(text string, order []repository.SongOrder) (*repository.SongKeyset, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", ErrInvalidInput)
+
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor songCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, invalid
+	}
+	if cursor.Sort != formatSort(order) {
+		return nil, fmt.Errorf("%w: the cursor was made for another sort", ErrInvalidInput)
+	}
+	if len(cursor.Values) != len(order) {
+		return nil, invalid
+	}
+
+	keyset := &repository.SongKeyset{ID: cursor.ID}
+	for i, field := range order {
+		value, err := decodeSortValue(cursor.Values[i], field.Field)
+		if err != nil {
+			return nil, invalid
+		}
+		keyset.Values = append(keyset.Values, value)
+	}
+	return keyset, nil
+}
+
+// decodeSortValue restores the type repository.SortValue gives the field.
+func decodeSortValue(raw json.RawMessage, field string) (interface{}, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+	switch field {
+	case models.SortID, models.SortTrackNumber:
+		var number int
+		err := json.Unmarshal(raw, &number)
+		return number, err
+	case models.SortReleaseDate, models.SortCreatedAt, models.SortUpdatedAt:
+		var moment time.Time
+		err := json.Unmarshal(raw, &moment)
+		return moment, err
+	default:
+		var text string
+		err := json.Unmarshal(raw, &text)
+		return text, err
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 30, 0, 123000000, time.UTC)
+	track := 3
+	song := models.Song{
+		ID:          42,
+		Group:       "Muse",
+		Song:        "Uprising",
+		ReleaseDate: &models.ReleaseDate{Time: time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC), Precision: models.PrecisionYear},
+		TrackNumber: &track,
+		CreatedAt:   created,
+	}
+
+	tests := []struct {
+		name  string
+		song  models.Song
+		order []repository.SongOrder
+	}{
+		{name: "id", song: song, order: []repository.SongOrder{{Field: models.SortID}}},
+		{name: "text fields", song: song, order: []repository.SongOrder{{Field: models.SortGroup}, {Field: models.SortSong, Desc: true}}},
+		{name: "release date", song: song, order: []repository.SongOrder{{Field: models.SortReleaseDate, Desc: true}}},
+		{name: "track number and time", song: song, order: []repository.SongOrder{{Field: models.SortTrackNumber}, {Field: models.SortCreatedAt}}},
+		{name: "missing values", song: models.Song{ID: 7}, order: []repository.SongOrder{{Field: models.SortReleaseDate}, {Field: models.SortTrackNumber}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyset, err := decodeCursor(encodeCursor(test.song, test.order), test.order)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if keyset.ID != test.song.ID {
+				t.Errorf("ID = %d, want %d", keyset.ID, test.song.ID)
+			}
+			for i, field := range test.order {
+				want := repository.SortValue(test.song, field.Field)
+				got := keyset.Values[i]
+				if moment, ok := want.(time.Time); ok {
+					if decoded, ok := got.(time.Time); !ok || !decoded.Equal(moment) {
+						t.Errorf("%s = %v, want %v", field.Field, got, want)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", field.Field, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	order := []repository.SongOrder{{Field: models.SortSong}}
+	song := models.Song{ID: 1, Song: "Uprising"}
+
+	tests := []struct {
+		name   string
+		cursor string
+		order  []repository.SongOrder
+	}{
+		{name: "not base64", cursor: "not a cursor!", order: order},
+		{name: "not json", cursor: "bm90IGpzb24", order: order},
+		{name: "another sort", cursor: encodeCursor(song, order), order: []repository.SongOrder{{Field: models.SortSong, Desc: true}}},
+		{
+			name:   "value of another type",
+			cursor: "eyJzb3J0IjoiaWQiLCJ2YWx1ZXMiOlsiYWJjIl0sImlkIjoxfQ",
+			order:  []repository.SongOrder{{Field: models.SortID}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := decodeCursor(test.cursor, test.order); !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("decodeCursor error = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
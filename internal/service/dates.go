@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testForWork/internal/models"
+	"time"
+)
+
+type dateLayout struct {
+	layout    string
+	precision string
+}
+
+// knownDateLayouts maps the layout names accepted in RELEASE_DATE_LAYOUTS
+// to Go layouts and the precision a value in that layout carries.
+var knownDateLayouts = map[string]dateLayout{
+	"DD.MM.YYYY": {layout: "02.01.2006", precision: models.PrecisionDay},
+	"YYYY-MM-DD": {layout: "2006-01-02", precision: models.PrecisionDay},
+	"YYYY-MM":    {layout: "2006-01", precision: models.PrecisionMonth},
+	"YYYY":       {layout: "2006", precision: models.PrecisionYear},
+	"RFC3339":    {layout: time.RFC3339, precision: models.PrecisionDay},
+}
+
+// DefaultDateLayouts is used when no layouts are configured.
+var DefaultDateLayouts = []string{"YYYY-MM-DD", "DD.MM.YYYY", "YYYY-MM", "YYYY", "RFC3339"}
+
+// DateParser normalizes release dates given in any of the configured layouts.
+type DateParser struct {
+	layouts []dateLayout
+}
+
+func NewDateParser(names []string) (*DateParser, error) {
+	if len(names) == 0 {
+		names = DefaultDateLayouts
+	}
+
+	parser := &DateParser{}
+	for _, name := range names {
+		layout, ok := knownDateLayouts[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown release date layout %q", name)
+		}
+		parser.layouts = append(parser.layouts, layout)
+	}
+	return parser, nil
+}
+
+// Parse returns nil for an empty value. The date is truncated to the start
+// of its period, so "2006" is stored as 2006-01-01 with year precision.
+func (parser *DateParser) Parse(value string) (*models.ReleaseDate, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range parser.layouts {
+		parsed, err := time.Parse(layout.layout, value)
+		if err != nil {
+			continue
+		}
+		date := time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 0, 0, 0, 0, time.UTC)
+		return &models.ReleaseDate{Time: date, Precision: layout.precision}, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported release date %q", ErrInvalidInput, value)
+}
+
+func formatReleaseDate(date *models.ReleaseDate) string {
+	if date == nil {
+		return ""
+	}
+	return date.String()
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"testForWork/internal/models"
+	"testing"
+	"time"
+)
+
+func TestDateParserParse(t *testing.T) {
+	day := func(year int, month time.Month, date int) time.Time {
+		return time.Date(year, month, date, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		layouts   []string
+		value     string
+		time      time.Time
+		precision string
+	}{
+		{name: "iso date", value: "2006-07-16", time: day(2006, 7, 16), precision: models.PrecisionDay},
+		{name: "dotted date", value: "16.07.2006", time: day(2006, 7, 16), precision: models.PrecisionDay},
+		{name: "month", value: "2006-07", time: day(2006, 7, 1), precision: models.PrecisionMonth},
+		{name: "year", value: " 2006 ", time: day(2006, 1, 1), precision: models.PrecisionYear},
+		{name: "rfc3339 drops the time", value: "2006-07-16T23:30:00+03:00", time: day(2006, 7, 16), precision: models.PrecisionDay},
+		{name: "configured layout", layouts: []string{"dd.mm.yyyy"}, value: "01.02.2003", time: day(2003, 2, 1), precision: models.PrecisionDay},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser, err := NewDateParser(test.layouts)
+			if err != nil {
+				t.Fatalf("NewDateParser: %v", err)
+			}
+			date, err := parser.Parse(test.value)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", test.value, err)
+			}
+			if !date.Time.Equal(test.time) || date.Precision != test.precision {
+				t.Errorf("Parse(%q) = %v %s, want %v %s", test.value, date.Time, date.Precision, test.time, test.precision)
+			}
+		})
+	}
+}
+
+func TestDateParserParseEmpty(t *testing.T) {
+	parser, _ := NewDateParser(nil)
+	if date, err := parser.Parse("  "); date != nil || err != nil {
+		t.Errorf("Parse of a blank value = %v, %v, want nil, nil", date, err)
+	}
+}
+
+func TestDateParserParseInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		layouts []string
+		value   string
+	}{
+		{name: "words", value: "last summer"},
+		{name: "no such day", value: "2006-02-30"},
+		{name: "layout not configured", layouts: []string{"YYYY"}, value: "2006-07-16"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser, err := NewDateParser(test.layouts)
+			if err != nil {
+				t.Fatalf("NewDateParser: %v", err)
+			}
+			if _, err := parser.Parse(test.value); !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("Parse(%q) error = %v, want ErrInvalidInput", test.value, err)
+			}
+		})
+	}
+}
+
+func TestNewDateParserUnknownLayout(t *testing.T) {
+	if _, err := NewDateParser([]string{"MM/DD/YYYY"}); err == nil {
+		t.Error("NewDateParser accepted an unknown layout")
+	}
+}
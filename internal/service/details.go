@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testForWork/internal/config"
+	"testForWork/internal/models"
+	"time"
+)
+
+const (
+	ProviderLocal  = "local"
+	ProviderRemote = "remote"
+	ProviderNone   = "none"
+)
+
+var ErrDetailsNotFound = errors.New("song details not found")
+
+// errFallbackDetails marks errors of fallback providers, so the answer of a
+// stand-in is not cached as the answer of the upstream.
+var errFallbackDetails = errors.New("fallback details")
+
+// DetailsProvider supplies the release date, text and link of a song.
+type DetailsProvider interface {
+	FetchDetails(ctx context.Context, group, song string) (*models.SongDetail, error)
+}
+
+// StatusReporter is implemented by providers guarded by a circuit breaker.
+type StatusReporter interface {
+	Status() *models.CircuitBreakerStatus
+}
+
+// wrappedProvider is implemented by providers decorating another one.
+type wrappedProvider interface {
+	Unwrap() DetailsProvider
+}
+
+// providerGroup is implemented by providers combining several others.
+type providerGroup interface {
+	Providers() []DetailsProvider
+}
+
+// findProviders walks the decorator chain and provider groups collecting
+// every provider of type T.
+func findProviders[T any](provider DetailsProvider) []T {
+	var found []T
+	if match, ok := provider.(T); ok {
+		found = append(found, match)
+	}
+	switch p := provider.(type) {
+	case wrappedProvider:
+		found = append(found, findProviders[T](p.Unwrap())...)
+	case providerGroup:
+		for _, child := range p.Providers() {
+			found = append(found, findProviders[T](child)...)
+		}
+	}
+	return found
+}
+
+// NewDetailsProvider builds the chain of providers selected in the
+// configuration, wrapped in a cache unless caching is disabled.
+func NewDetailsProvider(cfg config.DetailsConfig, dates *DateParser) (DetailsProvider, error) {
+	specs := cfg.Providers
+	if len(specs) == 0 {
+		specs = []string{cfg.Provider}
+	}
+
+	providers := make([]NamedProvider, 0, len(specs))
+	for _, spec := range specs {
+		named, err := newNamedProvider(cfg, spec)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, *named)
+	}
+
+	merging, err := NewMergingDetailsProvider(providers, cfg.Priorities, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	var provider DetailsProvider = merging
+	if cfg.CacheSize > 0 {
+		provider = NewCachingDetailsProvider(provider, cfg.CacheSize, cfg.CacheTTL, cfg.CacheNegativeTTL)
+	}
+	return provider, nil
+}
+
+// newNamedProvider understands "local", "none", "remote" (the MUSIC_API
+// upstream) and "name=url" for additional remote sources.
+func newNamedProvider(cfg config.DetailsConfig, spec string) (*NamedProvider, error) {
+	if name, baseURL, ok := strings.Cut(spec, "="); ok {
+		fallback, err := newFallbackProvider(cfg.Fallback)
+		if err != nil {
+			return nil, err
+		}
+		return &NamedProvider{Name: name, Provider: NewRemoteDetailsProvider(name, baseURL, cfg, fallback)}, nil
+	}
+
+	switch spec {
+	case ProviderLocal:
+		return &NamedProvider{Name: spec, Provider: &LocalDetailsProvider{}}, nil
+	case ProviderRemote:
+		fallback, err := newFallbackProvider(cfg.Fallback)
+		if err != nil {
+			return nil, err
+		}
+		return &NamedProvider{Name: spec, Provider: NewRemoteDetailsProvider(spec, cfg.MusicAPI, cfg, fallback)}, nil
+	case ProviderNone:
+		return &NamedProvider{Name: spec, Provider: &NoneDetailsProvider{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown details provider %q", spec)
+	}
+}
+
+func newFallbackProvider(name string) (DetailsProvider, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case ProviderLocal:
+		return &LocalDetailsProvider{}, nil
+	case ProviderNone:
+		return &NoneDetailsProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown fallback details provider %q", name)
+	}
+}
+
+// LocalDetailsProvider generates placeholder details without any network calls.
+type LocalDetailsProvider struct{}
+
+func (provider *LocalDetailsProvider) FetchDetails(ctx context.Context, group, song string) (*models.SongDetail, error) {
+	return &models.SongDetail{
+		ReleaseDate: time.Now().Format(dateFormat),
+		Text:        "Text placeholder for " + song,
+		Link:        fmt.Sprintf("https://example.com/%s/%s", group, song),
+	}, nil
+}
+
+// NoneDetailsProvider leaves songs without details.
+type NoneDetailsProvider struct{}
+
+func (provider *NoneDetailsProvider) FetchDetails(ctx context.Context, group, song string) (*models.SongDetail, error) {
+	return &models.SongDetail{}, nil
+}
+
+// RemoteDetailsProvider queries the external music API described in the spec.
+// Timeouts, 5xx and 429 responses are retried with exponential backoff, and
+// repeated failures trip a circuit breaker that fast-fails (or hands the call
+// to the fallback provider) until the upstream is probed again.
+type RemoteDetailsProvider struct {
+	baseURL        string
+	client         *http.Client
+	maxRetries     int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	breaker        *CircuitBreaker
+	fallback       DetailsProvider
+}
+
+// upstreamError is a failed call that is worth retrying.
+type upstreamError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *upstreamError) Error() string {
+	return e.err.Error()
+}
+
+func (e *upstreamError) Unwrap() error {
+	return e.err
+}
+
+func NewRemoteDetailsProvider(name, baseURL string, cfg config.DetailsConfig, fallback DetailsProvider) *RemoteDetailsProvider {
+	return &RemoteDetailsProvider{
+		baseURL:        baseURL,
+		client:         &http.Client{Timeout: cfg.Timeout},
+		maxRetries:     cfg.MaxRetries,
+		retryBaseDelay: cfg.RetryBaseDelay,
+		retryMaxDelay:  cfg.RetryMaxDelay,
+		breaker:        NewCircuitBreaker(name, cfg.BreakerThreshold, cfg.BreakerOpenTimeout),
+		fallback:       fallback,
+	}
+}
+
+func (provider *RemoteDetailsProvider) FetchDetails(ctx context.Context, group, song string) (*models.SongDetail, error) {
+	generation, err := provider.breaker.Allow()
+	if err != nil {
+		if provider.fallback != nil {
+			log.Printf("Music API unavailable (%v), using fallback details", err)
+			detail, err := provider.fallback.FetchDetails(ctx, group, song)
+			if err != nil {
+				return nil, fmt.Errorf("%w (%w)", err, errFallbackDetails)
+			}
+			detail.Fallback = true
+			return detail, nil
+		}
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		detail, err := provider.fetchOnce(ctx, group, song)
+		if ctx.Err() != nil {
+			// The caller gave up, this says nothing about the upstream.
+			provider.breaker.Cancel(generation)
+			return nil, fmt.Errorf("music API call aborted: %w", ctx.Err())
+		}
+		if err == nil {
+			provider.breaker.Success(generation)
+			return detail, nil
+		}
+
+		var retryable *upstreamError
+		if !errors.As(err, &retryable) {
+			// The upstream answered, it just did not like the request.
+			provider.breaker.Success(generation)
+			return nil, err
+		}
+
+		delay := provider.backoff(attempt, retryable.retryAfter)
+		if attempt >= provider.maxRetries {
+			provider.breaker.Failure(generation, err)
+			return nil, fmt.Errorf("music API failed after %d attempts: %w", attempt+1, err)
+		}
+
+		log.Printf("Music API attempt %d failed: %v, retrying in %s", attempt+1, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			provider.breaker.Cancel(generation)
+			return nil, fmt.Errorf("music API call aborted: %w", ctx.Err())
+		}
+	}
+}
+
+// Status exposes the circuit breaker state of the music API.
+func (provider *RemoteDetailsProvider) Status() *models.CircuitBreakerStatus {
+	return provider.breaker.Status()
+}
+
+func (provider *RemoteDetailsProvider) fetchOnce(ctx context.Context, group, song string) (*models.SongDetail, error) {
+	requestURL := fmt.Sprintf("%s/info?group=%s&song=%s",
+		provider.baseURL,
+		url.QueryEscape(group),
+		url.QueryEscape(song))
+	log.Printf("Fetching song details for %s", requestURL)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return nil, &upstreamError{err: fmt.Errorf("API query failed: %w", err)}
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests:
+		return nil, &upstreamError{
+			err:        fmt.Errorf("API returned status: %s", response.Status),
+			retryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+		}
+	case response.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: API returned status: %s", ErrDetailsNotFound, response.Status)
+	case response.StatusCode >= http.StatusInternalServerError:
+		return nil, &upstreamError{err: fmt.Errorf("API returned status: %s", response.Status)}
+	case response.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("API returned status: %s", response.Status)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, &upstreamError{err: fmt.Errorf("failed to read response: %w", err)}
+	}
+
+	var detail models.SongDetail
+	if err := json.Unmarshal(body, &detail); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &detail, nil
+}
+
+// backoff returns the delay before the next attempt: the Retry-After value
+// when the upstream sent one, exponential backoff with full jitter otherwise,
+// at most the maximum retry delay either way.
+func (provider *RemoteDetailsProvider) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, provider.retryMaxDelay)
+	}
+
+	delay := provider.retryBaseDelay << attempt
+	if delay <= 0 || delay > provider.retryMaxDelay {
+		delay = provider.retryMaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// parseRetryAfter understands both forms of the header: delay in seconds and HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
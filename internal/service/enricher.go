@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testForWork/internal/config"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+	"time"
+)
+
+// Enricher fills release date, text and link of pending songs in the
+// background. Songs are queued on creation and, to survive restarts and
+// full queues, pending rows are periodically picked up from the database.
+type Enricher struct {
+	service       *Service
+	workers       int
+	maxAttempts   int
+	sweepInterval time.Duration
+	retryDelay    time.Duration
+	retryMaxDelay time.Duration
+
+	queue  chan int
+	mu     sync.Mutex
+	queued map[int]bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// StartEnrichment launches the enrichment worker pool and resumes songs
+// that were left pending.
+func (service *Service) StartEnrichment(cfg config.EnrichmentConfig) {
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	queueSize := cfg.QueueSize
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	enricher := &Enricher{
+		service:       service,
+		workers:       workers,
+		maxAttempts:   cfg.MaxAttempts,
+		sweepInterval: cfg.SweepInterval,
+		retryDelay:    cfg.RetryDelay,
+		retryMaxDelay: cfg.RetryMaxDelay,
+		queue:         make(chan int, queueSize),
+		queued:        make(map[int]bool),
+	}
+	service.enricher = enricher
+
+	ctx, cancel := context.WithCancel(context.Background())
+	enricher.cancel = cancel
+
+	for i := 0; i < workers; i++ {
+		enricher.wg.Add(1)
+		go enricher.work(ctx)
+	}
+
+	enricher.wg.Add(1)
+	go enricher.sweep(ctx)
+
+	log.Printf("Started %d enrichment workers", workers)
+}
+
+// StopEnrichment stops the workers and waits for them until the context
+// expires. Songs in progress are aborted and stay pending for the next start.
+func (service *Service) StopEnrichment(ctx context.Context) error {
+	enricher := service.enricher
+	if enricher == nil {
+		return nil
+	}
+	enricher.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		enricher.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("Enrichment workers stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("enrichment workers did not stop: %w", ctx.Err())
+	}
+}
+
+// enqueue hands a song to the workers without blocking; when the queue is
+// full the song stays pending and is picked up by the next sweep.
+func (enricher *Enricher) enqueue(id int) {
+	enricher.mu.Lock()
+	defer enricher.mu.Unlock()
+
+	if enricher.queued[id] {
+		return
+	}
+
+	select {
+	case enricher.queue <- id:
+		enricher.queued[id] = true
+	default:
+		log.Printf("Enrichment queue is full, song %d will be picked up later", id)
+	}
+}
+
+func (enricher *Enricher) done(id int) {
+	enricher.mu.Lock()
+	defer enricher.mu.Unlock()
+	delete(enricher.queued, id)
+}
+
+func (enricher *Enricher) work(ctx context.Context) {
+	defer enricher.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-enricher.queue:
+			if err := enricher.enrich(ctx, id); err != nil {
+				if ctx.Err() != nil {
+					log.Printf("Enrichment of song %d interrupted by shutdown", id)
+				} else {
+					log.Printf("Error enriching song %d: %v", id, err)
+				}
+			}
+			enricher.done(id)
+		}
+	}
+}
+
+func (enricher *Enricher) sweep(ctx context.Context) {
+	defer enricher.wg.Done()
+
+	ticker := time.NewTicker(enricher.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := enricher.enqueuePending(ctx); err != nil {
+			log.Printf("Error loading pending songs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueuePending queues the pending songs whose retry, if any, is due.
+func (enricher *Enricher) enqueuePending(ctx context.Context) error {
+	now := time.Now()
+	songs, err := enricher.service.songs.List(ctx, repository.SongQuery{
+		EnrichmentStatus: models.EnrichmentPending,
+		EnrichmentDue:    &now,
+		Limit:            cap(enricher.queue),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, song := range songs {
+		enricher.enqueue(song.ID)
+	}
+	return nil
+}
+
+func (enricher *Enricher) enrich(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, enricher.service.timeouts.EnrichSong)
+	defer cancel()
+
+	song, err := enricher.service.songs.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if song.EnrichmentStatus != models.EnrichmentPending {
+		return nil
+	}
+
+	details, err := enricher.service.details.FetchDetails(ctx, song.Group, song.Song)
+	if err == nil {
+		err = enricher.store(ctx, id, details)
+	}
+	if errors.Is(err, context.Canceled) {
+		// Shutdown, not a failed attempt: the song stays pending.
+		return err
+	}
+	if err != nil {
+		return enricher.fail(song, err)
+	}
+
+	log.Printf("Enriched song %d", id)
+	return nil
+}
+
+func (enricher *Enricher) store(ctx context.Context, id int, details *models.SongDetail) error {
+	releaseDate, err := enricher.service.dates.Parse(details.ReleaseDate)
+	if err != nil {
+		return err
+	}
+
+	status, clearError := models.EnrichmentOK, ""
+	_, err = enricher.service.songs.Update(ctx, id, repository.SongUpdate{
+		SetReleaseDate:     true,
+		ReleaseDate:        releaseDate,
+		Text:               &details.Text,
+		Sections:           ParseSections(details.Text),
+		Revision:           enrichmentRevision(details),
+		Link:               &details.Link,
+		Sources:            details.Sources,
+		PreserveSource:     SourceManual,
+		EnrichmentStatus:   &status,
+		EnrichmentError:    &clearError,
+		CountAttempt:       true,
+		IfEnrichmentStatus: models.EnrichmentPending,
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		// Deleted or re-enriched meanwhile.
+		return nil
+	}
+	return err
+}
+
+// enrichmentRevision credits the text revision to the provider of the text.
+func enrichmentRevision(details *models.SongDetail) models.RevisionNote {
+	return models.RevisionNote{Author: details.Sources[models.FieldText], Comment: "enrichment"}
+}
+
+// fail records a failed attempt; the song stays pending, to be tried again
+// after a growing delay, until it runs out of attempts, or fails right away
+// when the provider does not know it.
+func (enricher *Enricher) fail(song *models.Song, cause error) error {
+	maxAttempts := enricher.maxAttempts
+	if errors.Is(cause, ErrDetailsNotFound) {
+		maxAttempts = 0
+	}
+	retryAt := time.Now().Add(enricher.retryDelayAfter(song.EnrichmentAttempts))
+
+	// The enrichment context may have expired already, the attempt must be
+	// recorded anyway.
+	err := enricher.service.songs.RecordEnrichmentFailure(context.Background(), song.ID, cause.Error(), maxAttempts, retryAt)
+	if err != nil {
+		return err
+	}
+	return cause
+}
+
+// retryDelayAfter returns the delay before the attempt following the failed
+// one, doubling with every earlier failure.
+func (enricher *Enricher) retryDelayAfter(failures int) time.Duration {
+	if enricher.retryDelay <= 0 {
+		return 0
+	}
+	delay := enricher.retryDelay << min(failures, 32)
+	if delay <= 0 || delay > enricher.retryMaxDelay {
+		delay = enricher.retryMaxDelay
+	}
+	return delay
+}
+
+// EnrichSong fetches fresh details for a stored song, bypassing the details
+// cache, and overwrites the fields the provider returned, reporting what
+// changed.
+func (service *Service) EnrichSong(ctx context.Context, id int) (*models.EnrichmentResult, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.EnrichSong)
+	defer cancel()
+
+	current, err := service.GetSongByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// An explicit re-enrichment wants what the provider says now, not a
+	// cached answer.
+	fetch := service.details.FetchDetails
+	if cache, ok := service.DetailsCache(); ok {
+		fetch = cache.Refresh
+	}
+	details, err := fetch(ctx, current.Group, current.Song)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDetailsFetch, err)
+	}
+
+	update := repository.SongUpdate{Sources: models.Sources{}}
+	if details.ReleaseDate != "" {
+		releaseDate, err := service.dates.Parse(details.ReleaseDate)
+		if err != nil {
+			return nil, err
+		}
+		update.SetReleaseDate, update.ReleaseDate = true, releaseDate
+		update.Sources[models.FieldReleaseDate] = details.Sources[models.FieldReleaseDate]
+	}
+	if details.Text != "" {
+		update.Text, update.Sections = &details.Text, ParseSections(details.Text)
+		update.Revision = enrichmentRevision(details)
+		update.Sources[models.FieldText] = details.Sources[models.FieldText]
+	}
+	if details.Link != "" {
+		update.Link = &details.Link
+		update.Sources[models.FieldLink] = details.Sources[models.FieldLink]
+	}
+	status, clearError := models.EnrichmentOK, ""
+	update.EnrichmentStatus, update.EnrichmentError = &status, &clearError
+
+	updated, err := service.songs.Update(ctx, id, update)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Re-enriched song %d", id)
+	return &models.EnrichmentResult{
+		ID:      id,
+		Changes: diffDetails(current, updated),
+	}, nil
+}
+
+// EnrichSongs re-enriches every song matching the GetSongs filters. A
+// failure on one song is reported in its result and does not stop the rest.
+func (service *Service) EnrichSongs(ctx context.Context, group, song string) ([]models.EnrichmentResult, error) {
+	if group == "" && song == "" {
+		return nil, fmt.Errorf("%w: group or song_name filter is required", ErrInvalidInput)
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.EnrichSongs)
+	defer cancel()
+
+	songs, err := service.songs.List(ctx, repository.SongQuery{Group: group, Song: song})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.EnrichmentResult, 0, len(songs))
+	for _, song := range songs {
+		id := song.ID
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := service.EnrichSong(ctx, id)
+		if err != nil {
+			log.Printf("Error re-enriching song %d: %v", id, err)
+			results = append(results, models.EnrichmentResult{ID: id, Error: err.Error()})
+			continue
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+func diffDetails(before, after *models.Song) map[string]models.FieldChange {
+	changes := make(map[string]models.FieldChange)
+	values := func(song *models.Song) map[string]string {
+		return map[string]string{
+			models.FieldReleaseDate: formatReleaseDate(song.ReleaseDate),
+			models.FieldText:        song.Text,
+			models.FieldLink:        song.Link,
+		}
+	}
+
+	beforeValues, afterValues := values(before), values(after)
+	for _, field := range models.DetailFields {
+		if beforeValues[field] != afterValues[field] {
+			changes[field] = models.FieldChange{
+				Before: beforeValues[field],
+				After:  afterValues[field],
+				Source: after.Sources[field],
+			}
+		}
+	}
+	return changes
+}
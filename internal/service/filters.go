@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+	"time"
+)
+
+// songQuery validates the song list filters and turns them into a
+// repository query.
+func (service *Service) songQuery(filter models.SongFilter) (repository.SongQuery, error) {
+	query := repository.SongQuery{
+		GroupID: filter.GroupID,
+		AlbumID: filter.AlbumID,
+		Group:   filter.Group,
+		Song:    filter.Song,
+	}
+
+	released, err := service.dates.Parse(filter.Released)
+	if err != nil {
+		return query, err
+	}
+	if released != nil {
+		end := released.End()
+		query.ReleasedFrom, query.ReleasedTo = &released.Time, &end
+	}
+	releasedFrom, err := service.dates.Parse(filter.ReleasedFrom)
+	if err != nil {
+		return query, err
+	}
+	if releasedFrom != nil && (query.ReleasedFrom == nil || releasedFrom.Time.After(*query.ReleasedFrom)) {
+		query.ReleasedFrom = &releasedFrom.Time
+	}
+	releasedTo, err := service.dates.Parse(filter.ReleasedTo)
+	if err != nil {
+		return query, err
+	}
+	if releasedTo != nil {
+		if end := releasedTo.End(); query.ReleasedTo == nil || end.Before(*query.ReleasedTo) {
+			query.ReleasedTo = &end
+		}
+	}
+
+	if query.CreatedFrom, err = service.parseTimeBound("created_from", filter.CreatedFrom, false); err != nil {
+		return query, err
+	}
+	if query.CreatedTo, err = service.parseTimeBound("created_to", filter.CreatedTo, true); err != nil {
+		return query, err
+	}
+	if query.UpdatedSince, err = service.parseTimeBound("updated_since", filter.UpdatedSince, false); err != nil {
+		return query, err
+	}
+	if query.HasLink, err = parseFlag("has_link", filter.HasLink); err != nil {
+		return query, err
+	}
+	if query.HasText, err = parseFlag("has_text", filter.HasText); err != nil {
+		return query, err
+	}
+
+	query.NameMatch = strings.ToLower(filter.NameMatch)
+	if query.NameMatch == "" {
+		query.NameMatch = models.NameMatchExact
+	}
+	if !slices.Contains(models.NameMatchModes, query.NameMatch) {
+		return query, fmt.Errorf("%w: name_match must be one of %s", ErrInvalidInput, strings.Join(models.NameMatchModes, ", "))
+	}
+	if query.Order, err = parseSort(filter.Sort); err != nil {
+		return query, err
+	}
+	if query.Genres, err = normalizeLabels(filter.Genres); err != nil {
+		return query, err
+	}
+	if query.Tags, err = normalizeLabels(filter.Tags); err != nil {
+		return query, err
+	}
+	if query.MatchAny, err = parseMatch(filter.Match); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// parseTimeBound reads an RFC 3339 time, or a date in a release date layout
+// standing for the start of its period, or the end of it for an upper bound.
+func (service *Service) parseTimeBound(name, value string, upper bool) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+
+	date, err := service.dates.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported %s %q", ErrInvalidInput, name, value)
+	}
+	bound := date.Time
+	if upper {
+		bound = date.End()
+	}
+	return &bound, nil
+}
+
+// parseFlag reads an optional boolean filter.
+func parseFlag(name, value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be true or false", ErrInvalidInput, name)
+	}
+	return &flag, nil
+}
+
+// parseSort reads a comma separated list of sort fields, each prefixed with
+// - to sort descending, like "-release_date,song".
+func parseSort(sort string) ([]repository.SongOrder, error) {
+	if strings.TrimSpace(sort) == "" {
+		return nil, nil
+	}
+
+	var order []repository.SongOrder
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		if !slices.Contains(models.SongSortFields, field) {
+			return nil, fmt.Errorf("%w: cannot sort by %q, allowed fields are %s", ErrInvalidInput, field, strings.Join(models.SongSortFields, ", "))
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%w: sort field %s is listed twice", ErrInvalidInput, field)
+		}
+		seen[field] = true
+		order = append(order, repository.SongOrder{Field: field, Desc: desc})
+	}
+	return order, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testForWork/internal/models"
+	"unicode"
+)
+
+// FindInText returns every occurrence of the phrase in the song text with
+// its verse, line and page, pages being limit verses long as in GetText.
+// Matches do not overlap and never span lines.
+func (service *Service) FindInText(ctx context.Context, id int, find models.TextFind, limit int) ([]models.TextMatch, error) {
+	phrase := []rune(strings.TrimSpace(find.Query))
+	if len(phrase) == 0 {
+		return nil, fmt.Errorf("%w: search phrase cannot be empty", ErrInvalidInput)
+	}
+	caseSensitive, err := parseFlag("case_sensitive", find.CaseSensitive)
+	if err != nil {
+		return nil, err
+	}
+	wholeWord, err := parseFlag("whole_word", find.WholeWord)
+	if err != nil {
+		return nil, err
+	}
+	fold := caseSensitive == nil || !*caseSensitive
+	if fold {
+		phrase = lowerRunes(phrase)
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetText)
+	defer cancel()
+
+	song, err := service.songs.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []models.TextMatch{}
+	for verseIndex, verse := range splitVerses(song.Text) {
+		for lineIndex, line := range strings.Split(verse, "\n") {
+			original := []rune(line)
+			runes := original
+			if fold {
+				runes = lowerRunes(original)
+			}
+			for start := 0; start+len(phrase) <= len(runes); start++ {
+				end := start + len(phrase)
+				if !equalRunes(runes[start:end], phrase) {
+					continue
+				}
+				if wholeWord != nil && *wholeWord && !wordBounded(runes, start, end) {
+					continue
+				}
+				matches = append(matches, models.TextMatch{
+					Verse: verseIndex,
+					Line:  lineIndex,
+					Start: start,
+					End:   end,
+					Page:  verseIndex/limit + 1,
+					Text:  string(original[start:end]),
+				})
+				start = end - 1
+			}
+		}
+	}
+	return matches, nil
+}
+
+// lowerRunes lowers every rune on its own, so offsets in the result match
+// the offsets in the original text, unlike strings.ToLower.
+func lowerRunes(runes []rune) []rune {
+	lowered := make([]rune, len(runes))
+	for i, r := range runes {
+		lowered[i] = unicode.ToLower(r)
+	}
+	return lowered
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+// isWordRune follows \w of regular expressions, extended to all letters.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// wordBounded tells whether runes[start:end] neither starts nor ends inside
+// a word.
+func wordBounded(runes []rune, start, end int) bool {
+	if start > 0 && isWordRune(runes[start-1]) && isWordRune(runes[start]) {
+		return false
+	}
+	if end < len(runes) && isWordRune(runes[end]) && isWordRune(runes[end-1]) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+	"time"
+)
+
+// minFormedYear rejects typos such as 199 for 1994.
+const minFormedYear = 1000
+
+func validateFormedYear(year *int) error {
+	if year == nil {
+		return nil
+	}
+	if *year < minFormedYear || *year > time.Now().Year() {
+		return fmt.Errorf("%w: formed_year must be between %d and %d", ErrInvalidInput, minFormedYear, time.Now().Year())
+	}
+	return nil
+}
+
+func (service *Service) CreateGroup(ctx context.Context, req models.GroupRequest) (*models.Group, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: group name cannot be empty", ErrInvalidInput)
+	}
+	if err := validateFormedYear(req.FormedYear); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.Groups)
+	defer cancel()
+
+	group, err := service.groups.Create(ctx, models.Group{
+		Name:        name,
+		FormedYear:  req.FormedYear,
+		Country:     strings.TrimSpace(req.Country),
+		Description: req.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Created new group: %d", group.ID)
+	return group, nil
+}
+
+func (service *Service) GetGroups(ctx context.Context, page, limit int) ([]models.Group, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.Groups)
+	defer cancel()
+
+	return service.groups.List(ctx, limit, (page-1)*limit)
+}
+
+func (service *Service) GetGroup(ctx context.Context, id int) (*models.Group, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.Groups)
+	defer cancel()
+
+	return service.groups.Get(ctx, id)
+}
+
+// UpdateGroup changes the group of all its songs at once, which is the point
+// of keeping groups apart from songs.
+func (service *Service) UpdateGroup(ctx context.Context, id int, req models.GroupUpdateRequest) (*models.Group, error) {
+	update := repository.GroupUpdate{
+		FormedYear:  req.FormedYear,
+		Country:     req.Country,
+		Description: req.Description,
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: group name cannot be empty", ErrInvalidInput)
+		}
+		update.Name = &name
+	}
+	if err := validateFormedYear(req.FormedYear); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.Groups)
+	defer cancel()
+
+	return service.groups.Update(ctx, id, update)
+}
+
+// DeleteGroup refuses to delete a group that still has songs.
+func (service *Service) DeleteGroup(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, service.timeouts.Groups)
+	defer cancel()
+
+	return service.groups.Delete(ctx, id)
+}
+
+func (service *Service) GetGroupSongs(ctx context.Context, id, page, limit int) ([]models.Song, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetSongs)
+	defer cancel()
+
+	if _, err := service.groups.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return service.songs.List(ctx, repository.SongQuery{
+		GroupID: id,
+		Limit:   limit,
+		Offset:  (page - 1) * limit,
+	})
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+)
+
+// normalizeLabel lower-cases a genre or tag name and collapses its spaces,
+// so "Hard  Rock" and "hard rock" are the same label.
+func normalizeLabel(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// normalizeLabels normalizes names and drops duplicates, keeping the order.
+func normalizeLabels(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		label := normalizeLabel(name)
+		if label == "" {
+			return nil, fmt.Errorf("%w: genre and tag names cannot be empty", ErrInvalidInput)
+		}
+		if !slices.Contains(normalized, label) {
+			normalized = append(normalized, label)
+		}
+	}
+	return normalized, nil
+}
+
+// parseMatch tells whether songs need only one of the filtered genres and tags.
+func parseMatch(match string) (bool, error) {
+	switch strings.ToLower(match) {
+	case "", models.MatchAll:
+		return false, nil
+	case models.MatchAny:
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w: match must be %s or %s", ErrInvalidInput, models.MatchAll, models.MatchAny)
+	}
+}
+
+func (service *Service) attachLabels(ctx context.Context, labels repository.LabelRepository, id int, req models.LabelsRequest) (*models.Song, error) {
+	names, err := normalizeLabels(req.Names)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: names cannot be empty", ErrInvalidInput)
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.Labels)
+	defer cancel()
+
+	if err := labels.Attach(ctx, id, names); err != nil {
+		return nil, err
+	}
+	return service.songs.Get(ctx, id)
+}
+
+func (service *Service) detachLabel(ctx context.Context, labels repository.LabelRepository, id int, name string) (*models.Song, error) {
+	label := normalizeLabel(name)
+	if label == "" {
+		return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidInput)
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.Labels)
+	defer cancel()
+
+	if err := labels.Detach(ctx, id, label); err != nil {
+		return nil, err
+	}
+	return service.songs.Get(ctx, id)
+}
+
+func (service *Service) listLabels(ctx context.Context, labels repository.LabelRepository) ([]models.Label, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.Labels)
+	defer cancel()
+
+	return labels.List(ctx)
+}
+
+// AttachGenres adds genres to the song and returns the updated song.
+func (service *Service) AttachGenres(ctx context.Context, id int, req models.LabelsRequest) (*models.Song, error) {
+	song, err := service.attachLabels(ctx, service.genres, id, req)
+	if err == nil {
+		log.Printf("Attached genres to song %d", id)
+	}
+	return song, err
+}
+
+// DetachGenre removes the genre from the song and returns the updated song.
+func (service *Service) DetachGenre(ctx context.Context, id int, name string) (*models.Song, error) {
+	return service.detachLabel(ctx, service.genres, id, name)
+}
+
+// GetGenres returns every genre with the number of songs it is attached to.
+func (service *Service) GetGenres(ctx context.Context) ([]models.Label, error) {
+	return service.listLabels(ctx, service.genres)
+}
+
+// AttachTags adds tags to the song and returns the updated song.
+func (service *Service) AttachTags(ctx context.Context, id int, req models.LabelsRequest) (*models.Song, error) {
+	song, err := service.attachLabels(ctx, service.tags, id, req)
+	if err == nil {
+		log.Printf("Attached tags to song %d", id)
+	}
+	return song, err
+}
+
+// DetachTag removes the tag from the song and returns the updated song.
+func (service *Service) DetachTag(ctx context.Context, id int, name string) (*models.Song, error) {
+	return service.detachLabel(ctx, service.tags, id, name)
+}
+
+// GetTags returns every tag with the number of songs it is attached to.
+func (service *Service) GetTags(ctx context.Context) ([]models.Label, error) {
+	return service.listLabels(ctx, service.tags)
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+)
+
+var (
+	// lrcTimeTag matches a line time tag like [01:02.34], [01:02] or [01:02.345].
+	lrcTimeTag = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+	// lrcWordTag matches an enhanced LRC word time tag like <01:02.34>.
+	lrcWordTag = regexp.MustCompile(`<(\d+):(\d{1,2})(?:[.:](\d{1,3}))?>`)
+	// lrcMetaTag matches a metadata line like [ar:Muse].
+	lrcMetaTag = regexp.MustCompile(`^\[([a-zA-Z#]+):(.*)\]$`)
+)
+
+// lrcTime converts the minutes, seconds and fraction of a time tag to
+// milliseconds. The fraction is read as a decimal, so "5" is 500 ms.
+func lrcTime(minutes, seconds, fraction string) (int, error) {
+	mins, err := strconv.Atoi(minutes)
+	if err != nil {
+		return 0, err
+	}
+	sec, _ := strconv.Atoi(seconds)
+	if sec >= 60 {
+		return 0, fmt.Errorf("seconds out of range in %s:%s", minutes, seconds)
+	}
+	ms := 0
+	if fraction != "" {
+		ms, _ = strconv.Atoi(fraction + strings.Repeat("0", 3-len(fraction)))
+	}
+	return (mins*60+sec)*1000 + ms, nil
+}
+
+// formatLRCTime writes hundredths like most players expect, and thousandths
+// only when the time needs them.
+func formatLRCTime(ms int) string {
+	if ms%10 == 0 {
+		return fmt.Sprintf("%02d:%02d.%02d", ms/60000, ms/1000%60, ms%1000/10)
+	}
+	return fmt.Sprintf("%02d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
+
+// ParseLRC reads LRC lyrics, including the enhanced format with a time tag
+// per word and lines with several time tags that are sung more than once.
+// Lines must come in time order, as must the words of a line; the offset
+// tag is applied to the times and dropped.
+func ParseLRC(text string) (*models.SyncedLyrics, error) {
+	lyrics := &models.SyncedLyrics{Tags: map[string]string{}, Lines: []models.SyncedLine{}}
+	offset := 0
+	previous := 0
+
+	for number, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		number++
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var times []int
+		rest := line
+		for match := lrcTimeTag.FindStringSubmatch(rest); match != nil; match = lrcTimeTag.FindStringSubmatch(rest) {
+			time, err := lrcTime(match[1], match[2], match[3])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidInput, number, err)
+			}
+			times = append(times, time)
+			rest = rest[len(match[0]):]
+		}
+
+		if len(times) == 0 {
+			match := lrcMetaTag.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("%w: line %d has no time tag", ErrInvalidInput, number)
+			}
+			key, value := strings.ToLower(match[1]), strings.TrimSpace(match[2])
+			if key == "offset" {
+				var err error
+				if offset, err = strconv.Atoi(strings.TrimPrefix(value, "+")); err != nil {
+					return nil, fmt.Errorf("%w: line %d: offset must be a number of milliseconds", ErrInvalidInput, number)
+				}
+				continue
+			}
+			lyrics.Tags[key] = value
+			continue
+		}
+
+		if times[0] < previous {
+			return nil, fmt.Errorf("%w: line %d: time %s is before the previous line", ErrInvalidInput, number, formatLRCTime(times[0]))
+		}
+		for i := 1; i < len(times); i++ {
+			if times[i] <= times[i-1] {
+				return nil, fmt.Errorf("%w: line %d: repeated times must be ascending", ErrInvalidInput, number)
+			}
+		}
+		previous = times[0]
+
+		words, err := parseLRCWords(rest, times[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidInput, number, err)
+		}
+		lineText := strings.TrimSpace(rest)
+		if words != nil {
+			lineText = wordsText(words)
+		}
+
+		// a line sung again later keeps the word timing relative to the line
+		for _, time := range times {
+			synced := models.SyncedLine{TimeMs: time, Text: lineText}
+			for _, word := range words {
+				synced.Words = append(synced.Words, models.SyncedWord{TimeMs: word.TimeMs + time - times[0], Text: word.Text})
+			}
+			lyrics.Lines = append(lyrics.Lines, synced)
+		}
+	}
+
+	if len(lyrics.Lines) == 0 {
+		return nil, fmt.Errorf("%w: lyrics have no timed lines", ErrInvalidInput)
+	}
+
+	sort.SliceStable(lyrics.Lines, func(i, j int) bool { return lyrics.Lines[i].TimeMs < lyrics.Lines[j].TimeMs })
+	if offset != 0 {
+		// a positive offset shows the lyrics earlier
+		shift := func(time int) int { return max(time-offset, 0) }
+		for i := range lyrics.Lines {
+			lyrics.Lines[i].TimeMs = shift(lyrics.Lines[i].TimeMs)
+			for j := range lyrics.Lines[i].Words {
+				lyrics.Lines[i].Words[j].TimeMs = shift(lyrics.Lines[i].Words[j].TimeMs)
+			}
+		}
+	}
+	return lyrics, nil
+}
+
+// parseLRCWords splits a line of enhanced LRC into timed words, nil when the
+// line has no word tags. Text before the first tag starts with the line.
+// A tag with no text after it is kept as an empty word marking where the
+// previous word ends.
+func parseLRCWords(line string, lineTime int) ([]models.SyncedWord, error) {
+	tags := lrcWordTag.FindAllStringSubmatchIndex(line, -1)
+	if tags == nil {
+		return nil, nil
+	}
+
+	words := []models.SyncedWord{}
+	if text := strings.TrimSpace(line[:tags[0][0]]); text != "" {
+		words = append(words, models.SyncedWord{TimeMs: lineTime, Text: text})
+	}
+
+	previous := lineTime
+	for i, tag := range tags {
+		time, err := lrcTime(line[tag[2]:tag[3]], line[tag[4]:tag[5]], submatch(line, tag, 3))
+		if err != nil {
+			return nil, err
+		}
+		if time < previous {
+			return nil, fmt.Errorf("word time %s is before the previous word", formatLRCTime(time))
+		}
+		previous = time
+
+		end := len(line)
+		if i+1 < len(tags) {
+			end = tags[i+1][0]
+		}
+		words = append(words, models.SyncedWord{TimeMs: time, Text: strings.TrimSpace(line[tag[1]:end])})
+	}
+	return words, nil
+}
+
+// submatch returns the nth group of a FindStringSubmatchIndex match, empty
+// when the group did not take part.
+func submatch(text string, match []int, n int) string {
+	if match[2*n] < 0 {
+		return ""
+	}
+	return text[match[2*n]:match[2*n+1]]
+}
+
+func wordsText(words []models.SyncedWord) string {
+	texts := make([]string, 0, len(words))
+	for _, word := range words {
+		if word.Text != "" {
+			texts = append(texts, word.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// FormatLRC writes synced lyrics as LRC, with word tags for lines that
+// have word timing.
+func FormatLRC(lyrics *models.SyncedLyrics) string {
+	var builder strings.Builder
+
+	keys := make([]string, 0, len(lyrics.Tags))
+	for key := range lyrics.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&builder, "[%s:%s]\n", key, lyrics.Tags[key])
+	}
+
+	for _, line := range lyrics.Lines {
+		fmt.Fprintf(&builder, "[%s]", formatLRCTime(line.TimeMs))
+		if len(line.Words) == 0 {
+			builder.WriteString(line.Text)
+		}
+		for i, word := range line.Words {
+			if i > 0 {
+				builder.WriteByte(' ')
+			}
+			fmt.Fprintf(&builder, "<%s>%s", formatLRCTime(word.TimeMs), word.Text)
+		}
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+// SyncedText derives the plain song text from synced lyrics: a line per
+// line, with pauses turned into blank lines between verses.
+func SyncedText(lyrics *models.SyncedLyrics) string {
+	var verses []string
+	var verse []string
+	for _, line := range lyrics.Lines {
+		if line.Text == "" {
+			if len(verse) > 0 {
+				verses = append(verses, strings.Join(verse, "\n"))
+				verse = nil
+			}
+			continue
+		}
+		verse = append(verse, line.Text)
+	}
+	if len(verse) > 0 {
+		verses = append(verses, strings.Join(verse, "\n"))
+	}
+	return strings.Join(verses, "\n\n")
+}
+
+// SetSyncedLyrics stores the LRC lyrics of the song and replaces the song
+// text with the text derived from them.
+func (service *Service) SetSyncedLyrics(ctx context.Context, id int, lrc string, note models.RevisionNote) (*models.SyncedLyrics, error) {
+	lyrics, err := ParseLRC(lrc)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.UpdateSong)
+	defer cancel()
+
+	text := SyncedText(lyrics)
+	_, err = service.songs.Update(ctx, id, repository.SongUpdate{
+		Text:         &text,
+		Sections:     ParseSections(text),
+		SyncedLyrics: lyrics,
+		Sources:      models.Sources{models.FieldText: SourceManual},
+		Revision:     note,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lyrics, nil
+}
+
+// GetSyncedLyrics returns ErrNotFound for an unknown song and
+// ErrSyncedLyricsNotFound when the song has no synced lyrics.
+func (service *Service) GetSyncedLyrics(ctx context.Context, id int) (*models.SyncedLyrics, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetText)
+	defer cancel()
+
+	if _, err := service.songs.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return service.synced.Get(ctx, id)
+}
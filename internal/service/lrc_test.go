@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testForWork/internal/models"
+	"testing"
+)
+
+func TestParseLRC(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		tags  map[string]string
+		lines []models.SyncedLine
+	}{
+		{
+			name: "plain lines",
+			text: "[ar:Muse]\n[00:01.50]First\n\n[00:03]Second\n[00:04.125]Third",
+			tags: map[string]string{"ar": "Muse"},
+			lines: []models.SyncedLine{
+				{TimeMs: 1500, Text: "First"},
+				{TimeMs: 3000, Text: "Second"},
+				{TimeMs: 4125, Text: "Third"},
+			},
+		},
+		{
+			name: "repeated line",
+			text: "[00:01.00][00:10.00]Chorus\n[00:05.00]Verse",
+			tags: map[string]string{},
+			lines: []models.SyncedLine{
+				{TimeMs: 1000, Text: "Chorus"},
+				{TimeMs: 5000, Text: "Verse"},
+				{TimeMs: 10000, Text: "Chorus"},
+			},
+		},
+		{
+			name: "word timing",
+			text: "[00:01.00]<00:01.00>Hello <00:01.50>world <00:02.00>\n[00:03.00]<00:03.20>Again",
+			tags: map[string]string{},
+			lines: []models.SyncedLine{
+				{TimeMs: 1000, Text: "Hello world", Words: []models.SyncedWord{
+					{TimeMs: 1000, Text: "Hello"}, {TimeMs: 1500, Text: "world"}, {TimeMs: 2000, Text: ""},
+				}},
+				{TimeMs: 3000, Text: "Again", Words: []models.SyncedWord{{TimeMs: 3200, Text: "Again"}}},
+			},
+		},
+		{
+			name: "repeated line with word timing",
+			text: "[00:01.00][00:05.00]La <00:01.50>la",
+			tags: map[string]string{},
+			lines: []models.SyncedLine{
+				{TimeMs: 1000, Text: "La la", Words: []models.SyncedWord{{TimeMs: 1000, Text: "La"}, {TimeMs: 1500, Text: "la"}}},
+				{TimeMs: 5000, Text: "La la", Words: []models.SyncedWord{{TimeMs: 5000, Text: "La"}, {TimeMs: 5500, Text: "la"}}},
+			},
+		},
+		{
+			name: "offset",
+			text: "[offset:+500]\r\n[00:00.20]Early\r\n[00:02.00]Late",
+			tags: map[string]string{},
+			lines: []models.SyncedLine{
+				{TimeMs: 0, Text: "Early"},
+				{TimeMs: 1500, Text: "Late"},
+			},
+		},
+		{
+			name:  "pause",
+			text:  "[00:01.00]Line\n[00:02.00]",
+			tags:  map[string]string{},
+			lines: []models.SyncedLine{{TimeMs: 1000, Text: "Line"}, {TimeMs: 2000, Text: ""}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lyrics, err := ParseLRC(test.text)
+			if err != nil {
+				t.Fatalf("ParseLRC: %v", err)
+			}
+			if !reflect.DeepEqual(lyrics.Tags, test.tags) {
+				t.Errorf("tags = %v, want %v", lyrics.Tags, test.tags)
+			}
+			if !reflect.DeepEqual(lyrics.Lines, test.lines) {
+				t.Errorf("lines = %+v, want %+v", lyrics.Lines, test.lines)
+			}
+		})
+	}
+}
+
+func TestParseLRCInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "empty", text: ""},
+		{name: "only tags", text: "[ar:Muse]\n[ti:Uprising]"},
+		{name: "no time tag", text: "[00:01.00]First\nSecond"},
+		{name: "seconds out of range", text: "[00:75.00]First"},
+		{name: "lines out of order", text: "[00:05.00]First\n[00:01.00]Second"},
+		{name: "repeated times out of order", text: "[00:05.00][00:01.00]First"},
+		{name: "words out of order", text: "[00:01.00]<00:03.00>First <00:02.00>second"},
+		{name: "bad offset", text: "[offset:soon]\n[00:01.00]First"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseLRC(test.text); !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("ParseLRC(%q) error = %v, want ErrInvalidInput", test.text, err)
+			}
+		})
+	}
+}
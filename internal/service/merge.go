@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"testForWork/internal/models"
+)
+
+// NamedProvider pairs a provider with the name recorded in song sources.
+type NamedProvider struct {
+	Name     string
+	Provider DetailsProvider
+}
+
+// MergingDetailsProvider asks an ordered chain of providers and takes each
+// field from the first provider that returns a non-empty, valid value. The
+// order can be overridden per field. Providers are queried lazily, so a
+// provider is only called when an earlier one could not fill some field.
+type MergingDetailsProvider struct {
+	providers  []NamedProvider
+	priorities map[string][]int
+	dates      *DateParser
+}
+
+// NewMergingDetailsProvider builds the chain. priorities maps a detail field
+// to provider names; fields without an explicit priority use the chain order.
+func NewMergingDetailsProvider(providers []NamedProvider, priorities map[string][]string, dates *DateParser) (*MergingDetailsProvider, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("at least one details provider is required")
+	}
+
+	indexes := make(map[string]int, len(providers))
+	for i, provider := range providers {
+		if _, ok := indexes[provider.Name]; ok {
+			return nil, fmt.Errorf("duplicate details provider %q", provider.Name)
+		}
+		indexes[provider.Name] = i
+	}
+
+	merging := &MergingDetailsProvider{
+		providers:  providers,
+		priorities: make(map[string][]int, len(models.DetailFields)),
+		dates:      dates,
+	}
+	for _, field := range models.DetailFields {
+		names, ok := priorities[field]
+		if !ok || len(names) == 0 {
+			for i := range providers {
+				merging.priorities[field] = append(merging.priorities[field], i)
+			}
+			continue
+		}
+		for _, name := range names {
+			i, ok := indexes[name]
+			if !ok {
+				return nil, fmt.Errorf("priority for %s references unknown details provider %q", field, name)
+			}
+			merging.priorities[field] = append(merging.priorities[field], i)
+		}
+	}
+	for field := range priorities {
+		if _, ok := merging.priorities[field]; !ok {
+			return nil, fmt.Errorf("unknown details field %q", field)
+		}
+	}
+	return merging, nil
+}
+
+func (merging *MergingDetailsProvider) FetchDetails(ctx context.Context, group, song string) (*models.SongDetail, error) {
+	details := make([]*models.SongDetail, len(merging.providers))
+	errs := make([]error, len(merging.providers))
+	fetched := make([]bool, len(merging.providers))
+
+	fetch := func(i int) *models.SongDetail {
+		if !fetched[i] && ctx.Err() == nil {
+			fetched[i] = true
+			details[i], errs[i] = merging.providers[i].Provider.FetchDetails(ctx, group, song)
+			if errs[i] != nil {
+				log.Printf("Details provider %s failed: %v", merging.providers[i].Name, errs[i])
+			}
+		}
+		return details[i]
+	}
+
+	merged := &models.SongDetail{Sources: models.Sources{}}
+	for _, field := range models.DetailFields {
+		for _, i := range merging.priorities[field] {
+			detail := fetch(i)
+			if detail == nil {
+				continue
+			}
+			value := detailField(detail, field)
+			if !merging.valid(field, value) {
+				continue
+			}
+			setDetailField(merged, field, value)
+			merged.Sources[field] = merging.providers[i].Name
+			break
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("details lookup aborted: %w", err)
+	}
+	answered := false
+	for _, detail := range details {
+		if detail != nil {
+			answered = true
+			// A stand-in answer may have hidden what the upstream would add.
+			merged.Fallback = merged.Fallback || detail.Fallback
+		}
+	}
+	if answered {
+		// Possibly with no field set: somebody answered with nothing to take.
+		return merged, nil
+	}
+	return nil, joinProviderErrors(errs)
+}
+
+// Names returns the names of the providers of the chain in order.
+func (merging *MergingDetailsProvider) Names() []string {
+	names := make([]string, len(merging.providers))
+	for i, provider := range merging.providers {
+		names[i] = provider.Name
+	}
+	return names
+}
+
+// DetailsProviderNames returns the names of the providers chained in the
+// provider built by NewDetailsProvider.
+func DetailsProviderNames(provider DetailsProvider) []string {
+	var names []string
+	for _, merging := range findProviders[*MergingDetailsProvider](provider) {
+		names = append(names, merging.Names()...)
+	}
+	return names
+}
+
+// Providers returns the providers of the chain in order.
+func (merging *MergingDetailsProvider) Providers() []DetailsProvider {
+	providers := make([]DetailsProvider, len(merging.providers))
+	for i, provider := range merging.providers {
+		providers[i] = provider.Provider
+	}
+	return providers
+}
+
+// joinProviderErrors reports "not found" only when every provider said so,
+// so a transient failure of one source is still retried.
+func joinProviderErrors(errs []error) error {
+	var failures []error
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ErrDetailsNotFound) {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) == 0 {
+		if slices.ContainsFunc(errs, func(err error) bool { return errors.Is(err, errFallbackDetails) }) {
+			return fmt.Errorf("%w (%w)", ErrDetailsNotFound, errFallbackDetails)
+		}
+		return ErrDetailsNotFound
+	}
+	return fmt.Errorf("all details providers failed: %w", errors.Join(failures...))
+}
+
+func detailField(detail *models.SongDetail, field string) string {
+	switch field {
+	case models.FieldReleaseDate:
+		return detail.ReleaseDate
+	case models.FieldText:
+		return detail.Text
+	case models.FieldLink:
+		return detail.Link
+	}
+	return ""
+}
+
+func setDetailField(detail *models.SongDetail, field, value string) {
+	switch field {
+	case models.FieldReleaseDate:
+		detail.ReleaseDate = value
+	case models.FieldText:
+		detail.Text = value
+	case models.FieldLink:
+		detail.Link = value
+	}
+}
+
+func (merging *MergingDetailsProvider) valid(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		return false
+	}
+
+	switch field {
+	case models.FieldReleaseDate:
+		_, err := merging.dates.Parse(value)
+		return err == nil
+	case models.FieldLink:
+		link, err := url.Parse(value)
+		return err == nil && (link.Scheme == "http" || link.Scheme == "https") && link.Host != ""
+	}
+	return true
+}
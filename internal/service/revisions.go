@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testForWork/internal/models"
+	"testForWork/internal/repository"
+)
+
+// GetRevisions returns a page of the text revisions of the song, newest
+// first and without their text.
+func (service *Service) GetRevisions(ctx context.Context, id, page, limit int) ([]models.TextRevision, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetText)
+	defer cancel()
+
+	if _, err := service.songs.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return service.revisions.List(ctx, id, limit, (page-1)*limit)
+}
+
+// GetRevision returns ErrNotFound for an unknown song and ErrRevisionNotFound
+// when the song has no such revision.
+func (service *Service) GetRevision(ctx context.Context, id, revision int) (*models.TextRevision, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetText)
+	defer cancel()
+
+	if _, err := service.songs.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return service.revisions.Get(ctx, id, revision)
+}
+
+// DiffRevisions compares the text of two revisions line by line. A zero to
+// stands for the latest revision and a negative from for the one before to;
+// revision 0 is the empty text the song started with.
+func (service *Service) DiffRevisions(ctx context.Context, id, from, to int) (*models.TextDiff, error) {
+	if to < 0 {
+		return nil, fmt.Errorf("%w: revision numbers start from 1", ErrInvalidInput)
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetText)
+	defer cancel()
+
+	if _, err := service.songs.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	if to == 0 {
+		latest, err := service.revisions.List(ctx, id, 1, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(latest) == 0 {
+			return nil, ErrRevisionNotFound
+		}
+		to = latest[0].Revision
+	}
+	if from < 0 {
+		from = to - 1
+	}
+
+	oldText, err := service.revisionText(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	newText, err := service.revisionText(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.TextDiff{From: from, To: to, Lines: lineDiff(splitLines(oldText), splitLines(newText))}
+	for _, line := range diff.Lines {
+		switch line.Op {
+		case models.DiffInsert:
+			diff.Added++
+		case models.DiffDelete:
+			diff.Removed++
+		}
+	}
+	return diff, nil
+}
+
+func (service *Service) revisionText(ctx context.Context, id, number int) (string, error) {
+	if number == 0 {
+		return "", nil
+	}
+	revision, err := service.revisions.Get(ctx, id, number)
+	if err != nil {
+		return "", err
+	}
+	return *revision.Text, nil
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// lineDiff finds the shortest edit from the old lines to the new ones
+// through their longest common subsequence. Deletions come before the
+// insertions that replace them. The subsequence is found with Hirschberg's
+// algorithm, in space linear in the number of lines.
+func lineDiff(old, new []string) []models.DiffLine {
+	lines := []models.DiffLine{}
+	diffLines(old, new, 0, 0, &lines)
+	return lines
+}
+
+// diffLines appends the edit of old to new, which start at the given
+// offsets of the whole texts.
+func diffLines(old, new []string, oldStart, newStart int, lines *[]models.DiffLine) {
+	equal := func(i, j int) {
+		*lines = append(*lines, models.DiffLine{Op: models.DiffEqual, OldLine: i + 1, NewLine: j + 1, Text: old[i-oldStart]})
+	}
+	deleted := func(from, to int) {
+		for i := from; i < to; i++ {
+			*lines = append(*lines, models.DiffLine{Op: models.DiffDelete, OldLine: oldStart + i + 1, Text: old[i]})
+		}
+	}
+	inserted := func(from, to int) {
+		for j := from; j < to; j++ {
+			*lines = append(*lines, models.DiffLine{Op: models.DiffInsert, NewLine: newStart + j + 1, Text: new[j]})
+		}
+	}
+
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		equal(oldStart+prefix, newStart+prefix)
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+
+	// the lines in between, which differ at both ends
+	oldEnd, newEnd := len(old)-suffix, len(new)-suffix
+	switch {
+	case prefix == oldEnd || prefix == newEnd:
+		deleted(prefix, oldEnd)
+		inserted(prefix, newEnd)
+	case oldEnd-prefix == 1:
+		k := slices.Index(new[prefix:newEnd], old[prefix])
+		if k < 0 {
+			deleted(prefix, oldEnd)
+			inserted(prefix, newEnd)
+			break
+		}
+		inserted(prefix, prefix+k)
+		equal(oldStart+prefix, newStart+prefix+k)
+		inserted(prefix+k+1, newEnd)
+	default:
+		// split the old lines in half and the new ones where the common
+		// subsequences of the halves add up to the longest
+		mid := prefix + (oldEnd-prefix)/2
+		forward := commonLengths(old[prefix:mid], new[prefix:newEnd], false)
+		backward := commonLengths(old[mid:oldEnd], new[prefix:newEnd], true)
+		split, best := 0, -1
+		for j := range forward {
+			if length := forward[j] + backward[j]; length > best {
+				split, best = j, length
+			}
+		}
+		split += prefix
+		diffLines(old[prefix:mid], new[prefix:split], oldStart+prefix, newStart+prefix, lines)
+		diffLines(old[mid:oldEnd], new[split:newEnd], oldStart+mid, newStart+split, lines)
+	}
+
+	for i := suffix; i > 0; i-- {
+		equal(oldStart+len(old)-i, newStart+len(new)-i)
+	}
+}
+
+// commonLengths returns, for every j, the length of the longest common
+// subsequence of old and new[:j], or of old and new[j:] when backward.
+func commonLengths(old, new []string, backward bool) []int {
+	at := func(lines []string, i int) string {
+		if backward {
+			return lines[len(lines)-1-i]
+		}
+		return lines[i]
+	}
+
+	row := make([]int, len(new)+1)
+	for i := range old {
+		diagonal := 0
+		for j := range new {
+			above := row[j+1]
+			if at(old, i) == at(new, j) {
+				row[j+1] = diagonal + 1
+			} else {
+				row[j+1] = max(row[j], above)
+			}
+			diagonal = above
+		}
+	}
+	if backward {
+		slices.Reverse(row)
+	}
+	return row
+}
+
+// RestoreRevision makes the text of an earlier revision the song text again,
+// recording it as a new revision.
+func (service *Service) RestoreRevision(ctx context.Context, id, revision int, note models.RevisionNote) (*models.Song, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.UpdateSong)
+	defer cancel()
+
+	if _, err := service.songs.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	restored, err := service.revisions.Get(ctx, id, revision)
+	if err != nil {
+		return nil, err
+	}
+	if note.Comment == "" {
+		note.Comment = fmt.Sprintf("restore revision %d", revision)
+	}
+
+	return service.songs.Update(ctx, id, repository.SongUpdate{
+		Text:     restored.Text,
+		Sections: ParseSections(*restored.Text),
+		Sources:  models.Sources{models.FieldText: SourceManual},
+		Revision: note,
+	})
+}
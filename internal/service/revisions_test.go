@@ -0,0 +1,140 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testForWork/internal/models"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	equal := func(oldLine, newLine int, text string) models.DiffLine {
+		return models.DiffLine{Op: models.DiffEqual, OldLine: oldLine, NewLine: newLine, Text: text}
+	}
+	deleted := func(oldLine int, text string) models.DiffLine {
+		return models.DiffLine{Op: models.DiffDelete, OldLine: oldLine, Text: text}
+	}
+	inserted := func(newLine int, text string) models.DiffLine {
+		return models.DiffLine{Op: models.DiffInsert, NewLine: newLine, Text: text}
+	}
+
+	tests := []struct {
+		name  string
+		old   string
+		new   string
+		lines []models.DiffLine
+	}{
+		{
+			name:  "both empty",
+			lines: []models.DiffLine{},
+		},
+		{
+			name:  "same",
+			old:   "a\nb",
+			new:   "a\nb",
+			lines: []models.DiffLine{equal(1, 1, "a"), equal(2, 2, "b")},
+		},
+		{
+			name:  "added",
+			new:   "a\nb",
+			lines: []models.DiffLine{inserted(1, "a"), inserted(2, "b")},
+		},
+		{
+			name:  "removed",
+			old:   "a\nb",
+			lines: []models.DiffLine{deleted(1, "a"), deleted(2, "b")},
+		},
+		{
+			name:  "replaced line",
+			old:   "a\nb\nc",
+			new:   "a\nx\nc",
+			lines: []models.DiffLine{equal(1, 1, "a"), deleted(2, "b"), inserted(2, "x"), equal(3, 3, "c")},
+		},
+		{
+			name:  "single old line kept among new ones",
+			old:   "b",
+			new:   "a\nb\nc",
+			lines: []models.DiffLine{inserted(1, "a"), equal(1, 2, "b"), inserted(3, "c")},
+		},
+		{
+			name: "moved line",
+			old:  "a\nb\nc\nd",
+			new:  "b\nc\na\nd",
+			lines: []models.DiffLine{
+				deleted(1, "a"), equal(2, 1, "b"), equal(3, 2, "c"), inserted(3, "a"), equal(4, 4, "d"),
+			},
+		},
+		{
+			name: "edits in the middle of a long text",
+			old:  "x\n1\n2\n3\n4\n5\n6\ny",
+			new:  "x\n1\n3\n4\nnew\n6\n7\ny",
+			lines: []models.DiffLine{
+				equal(1, 1, "x"), equal(2, 2, "1"), deleted(3, "2"), equal(4, 3, "3"), equal(5, 4, "4"),
+				deleted(6, "5"), inserted(5, "new"), equal(7, 6, "6"), inserted(7, "7"), equal(8, 8, "y"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines := lineDiff(splitLines(test.old), splitLines(test.new))
+			if !reflect.DeepEqual(lines, test.lines) {
+				t.Errorf("lineDiff = %+v, want %+v", lines, test.lines)
+			}
+		})
+	}
+}
+
+// TestLineDiffApplies checks that every diff turns the old text into the new
+// one and keeps as many lines as the longest common subsequence.
+func TestLineDiffApplies(t *testing.T) {
+	texts := []string{
+		"",
+		"a",
+		"a\nb\nc\nd\ne\nf",
+		"b\na\nd\nc\nf\ne",
+		"a\na\nb\nb\na\na",
+		"x\ny\na\nz\nc\ne",
+		"la\nla\nla\nverse\nla\nla",
+	}
+
+	for _, oldText := range texts {
+		for _, newText := range texts {
+			old, new := splitLines(oldText), splitLines(newText)
+			var kept, rebuilt []string
+			for _, line := range lineDiff(old, new) {
+				switch line.Op {
+				case models.DiffEqual:
+					kept = append(kept, line.Text)
+					rebuilt = append(rebuilt, line.Text)
+				case models.DiffInsert:
+					rebuilt = append(rebuilt, line.Text)
+				}
+			}
+			if strings.Join(rebuilt, "\n") != newText {
+				t.Errorf("diff of %q to %q rebuilds %q", oldText, newText, strings.Join(rebuilt, "\n"))
+			}
+			if want := longestCommon(old, new); len(kept) != want {
+				t.Errorf("diff of %q to %q keeps %d lines, want %d", oldText, newText, len(kept), want)
+			}
+		}
+	}
+}
+
+// longestCommon is the quadratic length of the longest common subsequence.
+func longestCommon(old, new []string) int {
+	lengths := make([][]int, len(old)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	return lengths[0][0]
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"testForWork/internal/models"
+)
+
+// sectionMarker matches marker lines like "[Chorus]" or "[Verse 2: Artist]".
+var sectionMarker = regexp.MustCompile(`^\[([^\[\]]+)\]$`)
+
+// sectionKeywords map words of section markers to section types, more
+// specific words first so "pre-chorus" is not taken for a chorus.
+var sectionKeywords = []struct {
+	word string
+	kind string
+}{
+	{"pre-chorus", models.SectionPreChorus},
+	{"pre chorus", models.SectionPreChorus},
+	{"prechorus", models.SectionPreChorus},
+	{"предприпев", models.SectionPreChorus},
+	{"chorus", models.SectionChorus},
+	{"refrain", models.SectionChorus},
+	{"припев", models.SectionChorus},
+	{"verse", models.SectionVerse},
+	{"куплет", models.SectionVerse},
+	{"bridge", models.SectionBridge},
+	{"бридж", models.SectionBridge},
+	{"intro", models.SectionIntro},
+	{"вступление", models.SectionIntro},
+	{"outro", models.SectionOutro},
+	{"концовка", models.SectionOutro},
+	{"hook", models.SectionHook},
+}
+
+func sectionType(label string) string {
+	label = strings.ToLower(label)
+	for _, keyword := range sectionKeywords {
+		if strings.Contains(label, keyword.word) {
+			return keyword.kind
+		}
+	}
+	return models.SectionOther
+}
+
+type rawSection struct {
+	label  string
+	marked bool
+	lines  []string
+}
+
+// ParseSections splits lyrics into typed sections. A marker line like
+// "[Chorus]" starts a section of that type, blank lines separate sections
+// otherwise. Unmarked sections are verses; in lyrics without any markers
+// the blocks that occur more than once are taken for choruses. A section
+// with the same lines as an earlier one of its type, or a marker with no
+// lines at all, is stored as a repeat of that earlier section.
+func ParseSections(text string) []models.Section {
+	var raw []rawSection
+	current := rawSection{}
+	flush := func() {
+		if len(current.lines) > 0 || current.marked {
+			raw = append(raw, current)
+		}
+		current = rawSection{}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		match := sectionMarker.FindStringSubmatch(line)
+		switch {
+		case match != nil:
+			flush()
+			current = rawSection{label: strings.TrimSpace(match[1]), marked: true}
+		case line == "":
+			// a blank line right after a marker still belongs to its section
+			if len(current.lines) > 0 {
+				flush()
+			}
+		default:
+			current.lines = append(current.lines, line)
+		}
+	}
+	flush()
+
+	marked := slices.ContainsFunc(raw, func(section rawSection) bool { return section.marked })
+	occurrences := map[string]int{}
+	if !marked {
+		for _, section := range raw {
+			occurrences[strings.Join(section.lines, "\n")]++
+		}
+	}
+
+	sections := make([]models.Section, 0, len(raw))
+	ordinals := map[string]int{}
+	for i, section := range raw {
+		kind := models.SectionVerse
+		switch {
+		case section.marked:
+			kind = sectionType(section.label)
+		case occurrences[strings.Join(section.lines, "\n")] > 1:
+			kind = models.SectionChorus
+		}
+		ordinals[kind]++
+
+		parsed := models.Section{
+			Position: i + 1,
+			Type:     kind,
+			Ordinal:  ordinals[kind],
+			Label:    section.label,
+			Lines:    section.lines,
+		}
+		if original := repeatedSection(sections, parsed); original != 0 {
+			parsed.Lines, parsed.RepeatOf = nil, &original
+		} else if parsed.Lines == nil {
+			parsed.Lines = []string{}
+		}
+		sections = append(sections, parsed)
+	}
+	return sections
+}
+
+// repeatedSection returns the position of the earlier section the section
+// repeats, or zero.
+func repeatedSection(earlier []models.Section, section models.Section) int {
+	for i := len(earlier) - 1; i >= 0; i-- {
+		candidate := earlier[i]
+		if candidate.Type != section.Type || candidate.RepeatOf != nil || len(candidate.Lines) == 0 {
+			continue
+		}
+		if len(section.Lines) == 0 || slices.Equal(candidate.Lines, section.Lines) {
+			return candidate.Position
+		}
+	}
+	return 0
+}
+
+// resolveSections fills in the lines of repeated sections.
+func resolveSections(sections []models.Section) []models.Section {
+	lines := make(map[int][]string, len(sections))
+	for i, section := range sections {
+		if section.RepeatOf == nil {
+			lines[section.Position] = section.Lines
+			continue
+		}
+		sections[i].Lines = lines[*section.RepeatOf]
+	}
+	return sections
+}
+
+// GetSections returns a page of the typed sections of the song, only of one
+// type when kind is not empty, and the number of such sections. Songs stored
+// before sections existed have their sections parsed but not saved, which is
+// left to ImportSections.
+func (service *Service) GetSections(ctx context.Context, id int, kind string, page, limit int) ([]models.Section, int, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if kind != "" && !slices.Contains(models.SectionTypes, kind) {
+		return nil, 0, fmt.Errorf("%w: section type must be one of %s", ErrInvalidInput, strings.Join(models.SectionTypes, ", "))
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetText)
+	defer cancel()
+
+	song, err := service.songs.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sections, err := service.sections.List(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(sections) == 0 && strings.TrimSpace(song.Text) != "" {
+		sections = ParseSections(song.Text)
+	}
+
+	sections = resolveSections(sections)
+	if kind != "" {
+		sections = slices.DeleteFunc(sections, func(section models.Section) bool { return section.Type != kind })
+	}
+
+	start := min((page-1)*limit, len(sections))
+	end := min(start+limit, len(sections))
+	return sections[start:end], len(sections), nil
+}
+
+// ImportSections parses the sections of every song that has text but no
+// sections yet, as stored before sections existed.
+func (service *Service) ImportSections(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.ImportSections)
+	defer cancel()
+
+	ids, err := service.sections.Unparsed(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, id := range ids {
+		song, err := service.songs.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return imported, err
+		}
+		stored, err := service.sections.Replace(ctx, id, song.Text, ParseSections(song.Text))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return imported, err
+		}
+		if stored {
+			imported++
+		}
+	}
+
+	log.Printf("Imported sections of %d songs", imported)
+	return imported, nil
+}
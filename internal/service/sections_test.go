@@ -0,0 +1,69 @@
+package service
+
+import (
+	"reflect"
+	"testForWork/internal/models"
+	"testing"
+)
+
+func TestParseSections(t *testing.T) {
+	repeat := func(position int) *int { return &position }
+
+	tests := []struct {
+		name     string
+		text     string
+		sections []models.Section
+	}{
+		{
+			name:     "empty",
+			text:     "",
+			sections: []models.Section{},
+		},
+		{
+			name: "marked",
+			text: "[Verse 1]\nOne\nTwo\n\n[Pre-Chorus]\nWait\n\n[Chorus]\nLa la\n\n[Verse 2: Guest]\nThree\n\n[Chorus]\n\n[Припев]\nLa la",
+			sections: []models.Section{
+				{Position: 1, Type: models.SectionVerse, Ordinal: 1, Label: "Verse 1", Lines: []string{"One", "Two"}},
+				{Position: 2, Type: models.SectionPreChorus, Ordinal: 1, Label: "Pre-Chorus", Lines: []string{"Wait"}},
+				{Position: 3, Type: models.SectionChorus, Ordinal: 1, Label: "Chorus", Lines: []string{"La la"}},
+				{Position: 4, Type: models.SectionVerse, Ordinal: 2, Label: "Verse 2: Guest", Lines: []string{"Three"}},
+				{Position: 5, Type: models.SectionChorus, Ordinal: 2, Label: "Chorus", RepeatOf: repeat(3)},
+				{Position: 6, Type: models.SectionChorus, Ordinal: 3, Label: "Припев", RepeatOf: repeat(3)},
+			},
+		},
+		{
+			name: "blank line after marker",
+			text: "[Intro]\n\nHum\n\n[Solo]\nNa",
+			sections: []models.Section{
+				{Position: 1, Type: models.SectionIntro, Ordinal: 1, Label: "Intro", Lines: []string{"Hum"}},
+				{Position: 2, Type: models.SectionOther, Ordinal: 1, Label: "Solo", Lines: []string{"Na"}},
+			},
+		},
+		{
+			name: "unmarked with a repeated block",
+			text: "One\nTwo\n\nLa la\nLa\n\nThree\r\n\r\nLa la\nLa",
+			sections: []models.Section{
+				{Position: 1, Type: models.SectionVerse, Ordinal: 1, Lines: []string{"One", "Two"}},
+				{Position: 2, Type: models.SectionChorus, Ordinal: 1, Lines: []string{"La la", "La"}},
+				{Position: 3, Type: models.SectionVerse, Ordinal: 2, Lines: []string{"Three"}},
+				{Position: 4, Type: models.SectionChorus, Ordinal: 2, RepeatOf: repeat(2)},
+			},
+		},
+		{
+			name: "empty marker without an earlier section",
+			text: "[Outro]",
+			sections: []models.Section{
+				{Position: 1, Type: models.SectionOutro, Ordinal: 1, Label: "Outro", Lines: []string{}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sections := ParseSections(test.text)
+			if !reflect.DeepEqual(sections, test.sections) {
+				t.Errorf("ParseSections(%q) = %+v, want %+v", test.text, sections, test.sections)
+			}
+		})
+	}
+}
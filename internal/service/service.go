@@ -2,281 +2,363 @@ package service
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"net/url"
+	"slices"
 	"strings"
+	"testForWork/internal/config"
 	"testForWork/internal/models"
+	"testForWork/internal/repository"
 	"time"
 )
 
 type Service struct {
-	db       *sql.DB
-	musicAPI string
+	songs     repository.SongRepository
+	groups    repository.GroupRepository
+	albums    repository.AlbumRepository
+	genres    repository.LabelRepository
+	tags      repository.LabelRepository
+	sections  repository.SectionRepository
+	synced    repository.SyncedLyricsRepository
+	revisions repository.TextRevisionRepository
+	details   DetailsProvider
+	dates     *DateParser
+	timeouts  config.TimeoutsConfig
+	enricher  *Enricher
 }
 
 const dateFormat = "2006-01-02"
 
-func NewService(db *sql.DB, musicAPI string) *Service {
+// SourceManual marks detail fields edited through the API.
+const SourceManual = "manual"
+
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrDetailsFetch = errors.New("failed to fetch song details")
+	ErrNotFound     = repository.ErrNotFound
+	ErrSongExists   = repository.ErrSongExists
+
+	ErrGroupNotFound = repository.ErrGroupNotFound
+	ErrGroupExists   = repository.ErrGroupExists
+	ErrGroupInUse    = repository.ErrGroupInUse
+	ErrAlbumNotFound = repository.ErrAlbumNotFound
+	ErrTrackGroup    = repository.ErrTrackGroup
+
+	ErrSyncedLyricsNotFound = repository.ErrSyncedLyricsNotFound
+	ErrRevisionNotFound     = repository.ErrRevisionNotFound
+)
+
+// SongConflictError points at the song that already has the name.
+type SongConflictError struct {
+	ID int
+}
+
+func (err *SongConflictError) Error() string {
+	return fmt.Sprintf("%s with id %d", ErrSongExists, err.ID)
+}
+
+func (err *SongConflictError) Unwrap() error {
+	return ErrSongExists
+}
+
+func NewService(repositories repository.Repositories, details DetailsProvider, dates *DateParser, timeouts config.TimeoutsConfig) *Service {
 	return &Service{
-		db:       db,
-		musicAPI: musicAPI,
+		songs:     repositories.Songs,
+		groups:    repositories.Groups,
+		albums:    repositories.Albums,
+		genres:    repositories.Genres,
+		tags:      repositories.Tags,
+		sections:  repositories.Sections,
+		synced:    repositories.Synced,
+		revisions: repositories.Revisions,
+		details:   details,
+		dates:     dates,
+		timeouts:  timeouts,
 	}
 }
 
-func (service *Service) CreateSong(group, song string) (*models.Song, error) {
+// withTimeout bounds an operation by its configured timeout on top of the
+// caller's context, so a client disconnect still cancels it right away.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// CreateSong stores the song right away with a pending enrichment status;
+// release date, text and link are filled in later by the enrichment workers.
+// When the group already has a song of that name, onConflict decides:
+// OnConflictError fails with a SongConflictError, OnConflictReturn returns
+// the existing song and OnConflictUpdate takes the new spelling of the name
+// and enriches the song again. The flag tells whether the song was created.
+func (service *Service) CreateSong(ctx context.Context, group, song, onConflict string) (*models.Song, bool, error) {
+	group = strings.TrimSpace(group)
+	song = strings.TrimSpace(song)
 	if group == "" || song == "" {
-		return nil, fmt.Errorf("group and song names cannot be empty")
+		return nil, false, fmt.Errorf("%w: group and song names cannot be empty", ErrInvalidInput)
 	}
+	onConflict = strings.ToLower(onConflict)
+	if onConflict == "" {
+		onConflict = models.OnConflictError
+	}
+	if !slices.Contains(models.OnConflictModes, onConflict) {
+		return nil, false, fmt.Errorf("%w: on_conflict must be one of %s", ErrInvalidInput, strings.Join(models.OnConflictModes, ", "))
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.CreateSong)
+	defer cancel()
 
 	log.Printf("Starting CreateSong for %s - %s", group, song)
-	details := service.generateLocalDetails(group, song)
 
-	// для подключенного API ->
-	/*details, err := service.fetchSongDetails(group, song)
+	resolved, err := service.groups.Resolve(ctx, group)
 	if err != nil {
-		log.Printf("Error fetching details: %v", err)
-		return nil, fmt.Errorf("failed to fetch song details: %w", err)
-	}*/
-
-	log.Printf("Received details: %+v", details)
+		return nil, false, err
+	}
 
-	releaseDate, err := time.Parse(dateFormat, details.ReleaseDate)
+	newSong, err := service.songs.Create(ctx, models.Song{
+		GroupID:          resolved.ID,
+		Song:             song,
+		EnrichmentStatus: models.EnrichmentPending,
+	})
+	if errors.Is(err, ErrSongExists) {
+		existing, err := service.resolveConflict(ctx, resolved.ID, song, onConflict)
+		return existing, false, err
+	}
 	if err != nil {
-		log.Printf("Date parsing error: %v | Input: %s", err, details.ReleaseDate)
-		return nil, fmt.Errorf("invalid date format: %w", err)
+		return nil, false, err
 	}
 
-	query := `INSERT INTO songs 
-    			("group_name", "song_name", "release_date", "text", "link") 
-			  VALUES 
-			    ($1, $2, $3, $4, $5) 
-			  RETURNING id, created_at, updated_at`
+	if service.enricher != nil {
+		service.enricher.enqueue(newSong.ID)
+	}
 
-	var newSong models.Song
-	err = service.db.QueryRowContext(
-		context.Background(), query, group, song, releaseDate, details.Text, details.Link,
-	).Scan(
-		&newSong.ID, &newSong.CreatedAt, &newSong.UpdatedAt,
-	)
+	log.Printf("Created new song: %d", newSong.ID)
+	return newSong, true, nil
+}
 
+func (service *Service) resolveConflict(ctx context.Context, groupID int, song, onConflict string) (*models.Song, error) {
+	existing, err := service.songs.FindByName(ctx, groupID, song)
 	if err != nil {
-		return nil, fmt.Errorf("database query failed: %w", err)
+		return nil, err
 	}
 
-	newSong.Group = group
-	newSong.Song = song
-	newSong.ReleaseDate = releaseDate
-	newSong.Text = details.Text
-	newSong.Link = details.Link
-
-	log.Printf("Created new song : %d", newSong)
-	return &newSong, nil
+	switch onConflict {
+	case models.OnConflictReturn:
+		return existing, nil
+	case models.OnConflictUpdate:
+		pending := models.EnrichmentPending
+		updated, err := service.songs.Update(ctx, existing.ID, repository.SongUpdate{Song: &song, EnrichmentStatus: &pending})
+		if err != nil {
+			return nil, err
+		}
+		if service.enricher != nil {
+			service.enricher.enqueue(updated.ID)
+		}
+		log.Printf("Updated existing song: %d", updated.ID)
+		return updated, nil
+	default:
+		return nil, &SongConflictError{ID: existing.ID}
+	}
 }
 
-func (service *Service) GetSongs(group, song string, page int, limit int) ([]models.Song, error) {
-	offset, query := (page-1)*limit, `
-		SELECT id, group_name, song_name, release_date, text, link, created_at, updated_at 
-		FROM songs
-		WHERE ($1 = '' OR group_name = $1) AND ($2 = '' OR song_name = $2) LIMIT $3 OFFSET $4`
+// GetSongs lists songs page by page. The release date filter matches songs
+// whose release period overlaps the requested one: "2006" matches a song
+// released on 2006-07-16 and a song known only as "2006-07", and vice versa.
+// Pages are found by number or, steadier under inserts, by the cursor of a
+// neighbouring page.
+func (service *Service) GetSongs(ctx context.Context, filter models.SongFilter, page int, limit int) (*models.SongPage, error) {
+	query, err := service.songQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// songs ordered by fuzzy score have no stable key to continue from
+	withCursors := len(query.Order) > 0 || query.NameMatch != models.NameMatchFuzzy
+	before := filter.Before != ""
+	switch {
+	case filter.After != "" && before:
+		return nil, fmt.Errorf("%w: after and before cannot be used together", ErrInvalidInput)
+	case filter.After != "" || before:
+		if !withCursors {
+			return nil, fmt.Errorf("%w: cursors need a sort in the fuzzy name match mode", ErrInvalidInput)
+		}
+		cursor := filter.After
+		if before {
+			cursor = filter.Before
+		}
+		if query.Keyset, err = decodeCursor(cursor, query.Order); err != nil {
+			return nil, err
+		}
+		query.Keyset.Before = before
+	default:
+		query.Offset = (page - 1) * limit
+	}
+	// one more song tells whether there is a page beyond this one
+	query.Limit = limit + 1
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetSongs)
+	defer cancel()
 
-	rows, err := service.db.QueryContext(context.Background(), query, group, song, limit, offset)
+	songs, err := service.songs.List(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("database query failed: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
-
-	var songs []models.Song
-	for rows.Next() {
-		var song models.Song
-		err := rows.Scan(
-			&song.ID,
-			&song.Group,
-			&song.Song,
-			&song.ReleaseDate,
-			&song.Text,
-			&song.Link,
-			&song.CreatedAt,
-			&song.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("row scan failed: %w", err)
+	more := len(songs) > limit
+	if more && before {
+		songs = songs[1:]
+	} else if more {
+		songs = songs[:limit]
+	}
+
+	result := &models.SongPage{Songs: songs}
+	if withCursors && len(songs) > 0 {
+		if before || more {
+			result.NextCursor = encodeCursor(songs[len(songs)-1], query.Order)
+		}
+		if (before && more) || filter.After != "" || (query.Keyset == nil && page > 1) {
+			result.PrevCursor = encodeCursor(songs[0], query.Order)
 		}
-		songs = append(songs, song)
 	}
-	return songs, nil
+	return result, nil
 }
 
-func (service *Service) GetText(id, page, limit int) ([]string, error) {
-	query := `SELECT text FROM songs WHERE id=$1`
-	var text string
-	err := service.db.QueryRowContext(context.Background(), query, id).Scan(&text)
+// CountSongs returns how many songs match the GetSongs filters.
+func (service *Service) CountSongs(ctx context.Context, filter models.SongFilter) (int, error) {
+	query, err := service.songQuery(filter)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetSongs)
+	defer cancel()
+
+	return service.songs.Count(ctx, query)
+}
+
+// SearchSongs finds songs by words of their name, group or lyrics, best
+// matches first.
+func (service *Service) SearchSongs(ctx context.Context, text string, page, limit int) ([]models.SongSearchResult, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: search query cannot be empty", ErrInvalidInput)
+	}
+
+	ctx, cancel := withTimeout(ctx, service.timeouts.SearchSongs)
+	defer cancel()
+
+	return service.songs.Search(ctx, repository.SongSearch{
+		Text:   text,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	})
+}
+
+// GetText returns a page of the song verses and the number of verses.
+func (service *Service) GetText(ctx context.Context, id, page, limit int) ([]string, int, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetText)
+	defer cancel()
+
+	song, err := service.songs.Get(ctx, id)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	verses := strings.Split(text, "\n\n")
+	verses := splitVerses(song.Text)
 	start := (page - 1) * limit
 	end := start + limit
 
 	if start > len(verses) {
-		return []string{}, nil
+		return []string{}, len(verses), nil
 	}
 	if end > len(verses) {
 		end = len(verses)
 	}
 
-	return verses[start:end], nil
+	return verses[start:end], len(verses), nil
 }
 
-func (service *Service) UpdateSong(id int, req models.SongUpdateRequest) (*models.Song, error) {
-	currentSong, err := service.GetSongByID(id)
-	if err != nil {
-		return nil, err
+// splitVerses splits the song text into verses at blank lines.
+func splitVerses(text string) []string {
+	if text == "" {
+		return []string{}
 	}
+	return strings.Split(text, "\n\n")
+}
 
-	var updates []string
-	var params []interface{}
-	counter := 1
+func (service *Service) UpdateSong(ctx context.Context, id int, req models.SongUpdateRequest) (*models.Song, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.UpdateSong)
+	defer cancel()
 
-	if req.Group != nil {
-		updates = append(updates, fmt.Sprintf("group_name = $%d", counter))
-		params = append(params, *req.Group)
-		counter++
+	update := repository.SongUpdate{
+		Song:     req.Song,
+		Text:     req.Text,
+		Link:     req.Link,
+		Sources:  models.Sources{},
+		Revision: models.RevisionNote{Author: req.Author, Comment: req.Comment},
 	}
-	if req.Song != nil {
-		updates = append(updates, fmt.Sprintf("song_name = $%d", counter))
-		params = append(params, *req.Song)
-		counter++
+	if req.Text != nil {
+		update.Sections = ParseSections(*req.Text)
+	}
+	if req.Group != nil {
+		name := strings.TrimSpace(*req.Group)
+		if name == "" {
+			return nil, fmt.Errorf("%w: group name cannot be empty", ErrInvalidInput)
+		}
+		group, err := service.groups.Resolve(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		update.GroupID = &group.ID
 	}
 	if req.ReleaseDate != nil {
-		rd, err := time.Parse(dateFormat, *req.ReleaseDate)
+		releaseDate, err := service.dates.Parse(*req.ReleaseDate)
 		if err != nil {
-			return nil, fmt.Errorf("invalid release date format: %w", err)
+			return nil, err
 		}
-		updates = append(updates, fmt.Sprintf("release_date = $%d", counter))
-		params = append(params, rd)
-		counter++
+		update.SetReleaseDate, update.ReleaseDate = true, releaseDate
+		update.Sources[models.FieldReleaseDate] = SourceManual
 	}
 	if req.Text != nil {
-		updates = append(updates, fmt.Sprintf("text = $%d", counter))
-		params = append(params, *req.Text)
-		counter++
+		update.Sources[models.FieldText] = SourceManual
 	}
 	if req.Link != nil {
-		updates = append(updates, fmt.Sprintf("link = $%d", counter))
-		params = append(params, *req.Link)
-		counter++
-	}
-
-	if len(updates) == 0 {
-		return currentSong, nil
-	}
-
-	query := fmt.Sprintf(
-		"UPDATE songs SET %s WHERE id = $%d RETURNING *",
-		strings.Join(updates, ", "),
-		counter,
-	)
-	params = append(params, id)
-
-	// Выполняем запрос
-	var updatedSong models.Song
-	err = service.db.QueryRow(query, params...).Scan(
-		&updatedSong.ID,
-		&updatedSong.Group,
-		&updatedSong.Song,
-		&updatedSong.ReleaseDate,
-		&updatedSong.Text,
-		&updatedSong.Link,
-		&updatedSong.CreatedAt,
-		&updatedSong.UpdatedAt,
-	)
-
-	if err != nil {
-		return nil, fmt.Errorf("database update failed: %w", err)
+		update.Sources[models.FieldLink] = SourceManual
 	}
 
-	return &updatedSong, nil
+	return service.songs.Update(ctx, id, update)
 }
 
-func (service *Service) DeleteSong(id int) error {
-	query := `DELETE FROM songs WHERE id = $1`
-	response, err := service.db.ExecContext(context.Background(), query, id)
-	if err != nil {
-		return fmt.Errorf("Database delete failed: %w", err)
-	}
-
-	rowsAffected, _ := response.RowsAffected()
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
+func (service *Service) DeleteSong(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, service.timeouts.DeleteSong)
+	defer cancel()
 
-	log.Printf("Deleted song with id %d", id)
-	return nil
+	return service.songs.Delete(ctx, id)
 }
 
-func (service *Service) generateLocalDetails(group, song string) *models.SongDetail {
-	return &models.SongDetail{
-		ReleaseDate: time.Now().Format("2006-01-02"),
-		Text:        "Text placeholder for " + song,
-		Link:        fmt.Sprintf("https://example.com/%s/%s", group, song),
+// DetailsStatus returns the circuit breaker state of every remote details provider.
+func (service *Service) DetailsStatus() []models.CircuitBreakerStatus {
+	statuses := []models.CircuitBreakerStatus{}
+	for _, reporter := range findProviders[StatusReporter](service.details) {
+		statuses = append(statuses, *reporter.Status())
 	}
+	return statuses
 }
 
-func (service *Service) fetchSongDetails(group, song string) (*models.SongDetail, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-
-	requestURL := fmt.Sprintf("%s/info?group=%s&song=%s",
-		service.musicAPI,
-		url.QueryEscape(group),
-		url.QueryEscape(song))
-	log.Printf("Fetching song details for %s", requestURL)
-
-	response, err := client.Get(requestURL)
-	if err != nil {
-		return nil, fmt.Errorf("API query failed: %w", err)
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status: %s", response.Status)
-	}
-
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to read response: %w", err)
+// DetailsCache returns the cache in front of the details provider, or false
+// when caching is disabled.
+func (service *Service) DetailsCache() (*CachingDetailsProvider, bool) {
+	caches := findProviders[*CachingDetailsProvider](service.details)
+	if len(caches) == 0 {
+		return nil, false
 	}
-
-	var detail models.SongDetail
-	if err := json.Unmarshal(body, &detail); err != nil {
-		return nil, fmt.Errorf("Failed to parse response: %w", err)
-	}
-
-	return &detail, nil
+	return caches[0], true
 }
 
-func (service *Service) GetSongByID(id int) (*models.Song, error) {
-	query := `SELECT id, group_name, song_name, release_date, text, link, created_at, updated_at FROM songs WHERE id = $1`
-
-	var song models.Song
-	err := service.db.QueryRow(query, id).Scan(
-		&song.ID,
-		&song.Group,
-		&song.Song,
-		&song.ReleaseDate,
-		&song.Text,
-		&song.Link,
-		&song.CreatedAt,
-		&song.UpdatedAt,
-	)
+func (service *Service) GetSongByID(ctx context.Context, id int) (*models.Song, error) {
+	ctx, cancel := withTimeout(ctx, service.timeouts.GetSongs)
+	defer cancel()
 
-	if err != nil {
-		return nil, fmt.Errorf("song not found: %w", err)
-	}
-	return &song, nil
+	return service.songs.Get(ctx, id)
 }